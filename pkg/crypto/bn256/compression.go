@@ -0,0 +1,295 @@
+package bn256
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/cloudflare/bn256"
+)
+
+const (
+	// CompressedSignatureLength is the length of signatures marshaled with MarshalCompressed:
+	// a version byte followed by 32 bytes of the x coordinate.
+	CompressedSignatureLength = 1 + coordLength
+	// CompressedVerificationKeyLength is the length of verification keys marshaled with MarshalCompressed:
+	// a version byte followed by 64 bytes of the x coordinate.
+	CompressedVerificationKeyLength = 1 + 2*coordLength
+)
+
+const coordLength = 32
+
+// Version bytes of the compressed encoding. They determine the choice of the y coordinate,
+// in the same manner as in the SEC 1 encoding of elliptic curve points.
+const (
+	versionInfinity byte = 0x00
+	versionEven     byte = 0x02
+	versionOdd      byte = 0x03
+)
+
+var (
+	errVersion = errors.New("unknown version of compressed encoding")
+	errNoPoint = errors.New("no point with the given x coordinate")
+)
+
+// MarshalCompressed marshals the signature using only its x coordinate.
+func (s *Signature) MarshalCompressed() []byte {
+	return compressG1(s.G1.Marshal())
+}
+
+// MarshalCompressed marshals the verification key using only its x coordinate.
+func (vk *VerificationKey) MarshalCompressed() []byte {
+	return compressG2(vk.key.Marshal())
+}
+
+// UnmarshalCompressed reads a signature marshaled with MarshalCompressed.
+// The regular encoding is not accepted, it has to be read with Unmarshal.
+func (s *Signature) UnmarshalCompressed(data []byte) (*Signature, error) {
+	if len(data) != CompressedSignatureLength {
+		return s, errors.New("wrong length of compressed signature")
+	}
+	decompressed, err := decompressG1(data)
+	if err != nil {
+		return s, err
+	}
+	_, err = s.G1.Unmarshal(decompressed)
+	return s, err
+}
+
+// UnmarshalCompressed reads a verification key marshaled with MarshalCompressed.
+// The regular encoding is not accepted, it has to be read with Unmarshal.
+func (vk *VerificationKey) UnmarshalCompressed(data []byte) (*VerificationKey, error) {
+	if len(data) != CompressedVerificationKeyLength {
+		return vk, errors.New("wrong length of compressed verification key")
+	}
+	decompressed, err := decompressG2(data)
+	if err != nil {
+		return vk, err
+	}
+	_, err = vk.key.Unmarshal(decompressed)
+	return vk, err
+}
+
+// Constants of the curves, y^2 = x^3 + curveB for G1 and y^2 = x^3 + twistB for G2,
+// computed from the generators.
+var (
+	curveB = func() *big.Int {
+		x, y := coords(new(bn256.G1).ScalarBaseMult(big.NewInt(1)).Marshal())
+		return fpSub(fpMul(y, y), fpMul(x, fpMul(x, x)))
+	}()
+	twistB = func() *fp2 {
+		x, y := coords2(new(bn256.G2).ScalarBaseMult(big.NewInt(1)).Marshal())
+		return y.mul(y).sub(x.mul(x).mul(x))
+	}()
+)
+
+func compressG1(data []byte) []byte {
+	result := make([]byte, CompressedSignatureLength)
+	if isZero(data) {
+		return result
+	}
+	_, y := coords(data)
+	result[0] = versionEven
+	if y.Bit(0) == 1 {
+		result[0] = versionOdd
+	}
+	copy(result[1:], data[:coordLength])
+	return result
+}
+
+func decompressG1(data []byte) ([]byte, error) {
+	result := make([]byte, 2*coordLength)
+	switch data[0] {
+	case versionInfinity:
+		if !isZero(data[1:]) {
+			return nil, errVersion
+		}
+		return result, nil
+	case versionEven, versionOdd:
+	default:
+		return nil, errVersion
+	}
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(bn256.P) >= 0 {
+		return nil, errNoPoint
+	}
+	rhs := fpAdd(fpMul(x, fpMul(x, x)), curveB)
+	y := new(big.Int).ModSqrt(rhs, bn256.P)
+	if y == nil {
+		return nil, errNoPoint
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(bn256.P, y)
+	}
+	putCoord(result[:coordLength], x)
+	putCoord(result[coordLength:], y)
+	return result, nil
+}
+
+func compressG2(data []byte) []byte {
+	result := make([]byte, CompressedVerificationKeyLength)
+	if isZero(data) {
+		return result
+	}
+	_, y := coords2(data)
+	result[0] = versionEven
+	if y.parity() == 1 {
+		result[0] = versionOdd
+	}
+	copy(result[1:], data[:2*coordLength])
+	return result
+}
+
+func decompressG2(data []byte) ([]byte, error) {
+	result := make([]byte, 4*coordLength)
+	switch data[0] {
+	case versionInfinity:
+		if !isZero(data[1:]) {
+			return nil, errVersion
+		}
+		return result, nil
+	case versionEven, versionOdd:
+	default:
+		return nil, errVersion
+	}
+	x := &fp2{
+		im: new(big.Int).SetBytes(data[1 : 1+coordLength]),
+		re: new(big.Int).SetBytes(data[1+coordLength:]),
+	}
+	if x.im.Cmp(bn256.P) >= 0 || x.re.Cmp(bn256.P) >= 0 {
+		return nil, errNoPoint
+	}
+	y := x.mul(x).mul(x).add(twistB).sqrt()
+	if y == nil {
+		return nil, errNoPoint
+	}
+	if y.parity() != uint(data[0]&1) {
+		y = y.neg()
+	}
+	copy(result, data[1:])
+	putCoord(result[2*coordLength:3*coordLength], y.im)
+	putCoord(result[3*coordLength:], y.re)
+	return result, nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func coords(data []byte) (x, y *big.Int) {
+	return new(big.Int).SetBytes(data[:coordLength]), new(big.Int).SetBytes(data[coordLength : 2*coordLength])
+}
+
+// coords2 reads coordinates of a marshaled point of G2. Elements of the quadratic extension
+// are marshaled with the imaginary part first.
+func coords2(data []byte) (x, y *fp2) {
+	x = &fp2{
+		im: new(big.Int).SetBytes(data[:coordLength]),
+		re: new(big.Int).SetBytes(data[coordLength : 2*coordLength]),
+	}
+	y = &fp2{
+		im: new(big.Int).SetBytes(data[2*coordLength : 3*coordLength]),
+		re: new(big.Int).SetBytes(data[3*coordLength : 4*coordLength]),
+	}
+	return x, y
+}
+
+func putCoord(dst []byte, v *big.Int) {
+	b := v.Bytes()
+	copy(dst[len(dst)-len(b):], b)
+}
+
+func fpAdd(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, bn256.P)
+}
+
+func fpSub(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, bn256.P)
+}
+
+func fpMul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, bn256.P)
+}
+
+// fp2 is an element re + im*i of the quadratic extension of the base field, where i^2 = -1.
+type fp2 struct {
+	re, im *big.Int
+}
+
+func (a *fp2) add(b *fp2) *fp2 {
+	return &fp2{fpAdd(a.re, b.re), fpAdd(a.im, b.im)}
+}
+
+func (a *fp2) sub(b *fp2) *fp2 {
+	return &fp2{fpSub(a.re, b.re), fpSub(a.im, b.im)}
+}
+
+func (a *fp2) neg() *fp2 {
+	zero := big.NewInt(0)
+	return &fp2{fpSub(zero, a.re), fpSub(zero, a.im)}
+}
+
+func (a *fp2) mul(b *fp2) *fp2 {
+	return &fp2{
+		fpSub(fpMul(a.re, b.re), fpMul(a.im, b.im)),
+		fpAdd(fpMul(a.re, b.im), fpMul(a.im, b.re)),
+	}
+}
+
+func (a *fp2) exp(e *big.Int) *fp2 {
+	result := &fp2{big.NewInt(1), big.NewInt(0)}
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		result = result.mul(result)
+		if e.Bit(i) == 1 {
+			result = result.mul(a)
+		}
+	}
+	return result
+}
+
+func (a *fp2) equal(b *fp2) bool {
+	return a.re.Cmp(b.re) == 0 && a.im.Cmp(b.im) == 0
+}
+
+// parity of the imaginary part, or of the real part if the former is zero.
+func (a *fp2) parity() uint {
+	if a.im.Sign() != 0 {
+		return a.im.Bit(0)
+	}
+	return a.re.Bit(0)
+}
+
+// sqrt returns a square root of a, or nil if there is none.
+// It uses the algorithm for p = 3 mod 4 by Adj and Rodríguez-Henríquez.
+func (a *fp2) sqrt() *fp2 {
+	one := big.NewInt(1)
+	minusOne := &fp2{fpSub(big.NewInt(0), one), big.NewInt(0)}
+	e := new(big.Int).Sub(bn256.P, big.NewInt(3))
+	e.Rsh(e, 2)
+	a1 := a.exp(e)
+	alpha := a1.mul(a1.mul(a))
+	conj := &fp2{alpha.re, fpSub(big.NewInt(0), alpha.im)}
+	if conj.mul(alpha).equal(minusOne) {
+		return nil
+	}
+	x0 := a1.mul(a)
+	var x *fp2
+	if alpha.equal(minusOne) {
+		x = (&fp2{big.NewInt(0), one}).mul(x0)
+	} else {
+		e = new(big.Int).Sub(bn256.P, one)
+		e.Rsh(e, 1)
+		x = alpha.add(&fp2{one, big.NewInt(0)}).exp(e).mul(x0)
+	}
+	if !x.mul(x).equal(a) {
+		return nil
+	}
+	return x
+}
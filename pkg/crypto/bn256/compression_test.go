@@ -0,0 +1,89 @@
+package bn256_test
+
+import (
+	"math/big"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	var (
+		vk   *VerificationKey
+		sk   *SecretKey
+		data []byte
+	)
+	BeforeEach(func() {
+		var err error
+		vk, sk, err = GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		data = []byte("19890604")
+	})
+	Context("A signature", func() {
+		It("Should have the compressed length", func() {
+			Expect(sk.Sign(data).MarshalCompressed()).To(HaveLen(CompressedSignatureLength))
+		})
+		It("Should be verified after compression and decompression", func() {
+			for i := 0; i < 10; i++ {
+				msg := append(data, byte(i))
+				sgn, err := new(Signature).UnmarshalCompressed(sk.Sign(msg).MarshalCompressed())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sgn.Marshal()).To(Equal(sk.Sign(msg).Marshal()))
+				Expect(vk.Verify(sgn, msg)).To(BeTrue())
+			}
+		})
+		It("Should be read only by the unmarshalling of its own encoding", func() {
+			_, err := new(Signature).UnmarshalCompressed(sk.Sign(data).Marshal())
+			Expect(err).To(HaveOccurred())
+			_, err = new(Signature).Unmarshal(sk.Sign(data).MarshalCompressed())
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should not be read with an unknown version byte", func() {
+			enc := sk.Sign(data).MarshalCompressed()
+			enc[0] = 0x07
+			_, err := new(Signature).UnmarshalCompressed(enc)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("A verification key", func() {
+		It("Should have the compressed length", func() {
+			Expect(vk.MarshalCompressed()).To(HaveLen(CompressedVerificationKeyLength))
+		})
+		It("Should be the same after compression and decompression", func() {
+			for i := 0; i < 10; i++ {
+				key, _, err := GenerateKeys()
+				Expect(err).NotTo(HaveOccurred())
+				key2, err := new(VerificationKey).UnmarshalCompressed(key.MarshalCompressed())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(key2.Marshal()).To(Equal(key.Marshal()))
+			}
+		})
+		It("Should be read from the compressed encoding of a known key", func() {
+			enc := NewVerificationKey(big.NewInt(2137)).MarshalCompressed()
+			key, err := new(VerificationKey).UnmarshalCompressed(enc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.Marshal()).To(Equal(NewVerificationKey(big.NewInt(2137)).Marshal()))
+		})
+		It("Should not be read when the x coordinate is not on the curve", func() {
+			enc := vk.MarshalCompressed()
+			found := false
+			for i := 0; i < 20 && !found; i++ {
+				enc[len(enc)-1]++
+				if _, err := new(VerificationKey).UnmarshalCompressed(enc); err != nil {
+					found = true
+				}
+			}
+			Expect(found).To(BeTrue())
+		})
+	})
+	Context("The zero element", func() {
+		It("Should be compressed and decompressed", func() {
+			zero := NewVerificationKey(big.NewInt(0))
+			key, err := new(VerificationKey).UnmarshalCompressed(zero.MarshalCompressed())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.Marshal()).To(Equal(zero.Marshal()))
+		})
+	})
+})
@@ -10,3 +10,6 @@ var Order = bn256.Order
 // SignatureLength is the length of the returned signatures after marshaling.
 // It is not explicitly defined in the underlaying package, but it is always 64.
 const SignatureLength = 64
+
+// scalarLength is the length of marshaled elements of the scalar field.
+const scalarLength = 32
@@ -7,3 +7,7 @@ import (
 func hash(msg []byte) *bn256.G1 {
 	return bn256.HashG1(msg, []byte("az-sig"))
 }
+
+func hashVRF(msg []byte) *bn256.G1 {
+	return bn256.HashG1(msg, []byte("az-vrf"))
+}
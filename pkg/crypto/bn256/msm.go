@@ -0,0 +1,139 @@
+package bn256
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+
+	"github.com/cloudflare/bn256"
+)
+
+// MultiMulSignatures returns the sum of sgns[i] multiplied by scalars[i].
+// Both slices should have the same, nonzero length.
+func MultiMulSignatures(sgns []*Signature, scalars []*big.Int) *Signature {
+	elems := make([]element, len(sgns))
+	for i, s := range sgns {
+		elems[i] = g1Elem{&s.G1}
+	}
+	result := multiMul(elems, scalars)
+	if result == nil {
+		return &Signature{*new(bn256.G1).ScalarBaseMult(big.NewInt(0))}
+	}
+	return &Signature{*new(bn256.G1).Set(result.(g1Elem).p)}
+}
+
+// MultiMulVerificationKeys returns the sum of vks[i] multiplied by scalars[i].
+// Both slices should have the same, nonzero length.
+func MultiMulVerificationKeys(vks []*VerificationKey, scalars []*big.Int) *VerificationKey {
+	elems := make([]element, len(vks))
+	for i, vk := range vks {
+		elems[i] = g2Elem{&vk.key}
+	}
+	result := multiMul(elems, scalars)
+	if result == nil {
+		return &VerificationKey{*new(bn256.G2).ScalarBaseMult(big.NewInt(0))}
+	}
+	return &VerificationKey{*new(bn256.G2).Set(result.(g2Elem).p)}
+}
+
+// element is a point of G1 or G2. A nil element represents zero.
+type element interface {
+	add(element) element
+}
+
+type g1Elem struct {
+	p *bn256.G1
+}
+
+func (e g1Elem) add(o element) element {
+	return g1Elem{new(bn256.G1).Add(e.p, o.(g1Elem).p)}
+}
+
+type g2Elem struct {
+	p *bn256.G2
+}
+
+func (e g2Elem) add(o element) element {
+	return g2Elem{new(bn256.G2).Add(e.p, o.(g2Elem).p)}
+}
+
+func add(a, b element) element {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return a.add(b)
+}
+
+// windowSize returns the number of bits processed at once by the bucket method for n points.
+func windowSize(n int) uint {
+	c := bits.Len(uint(n))
+	if c < 5 {
+		return 2
+	}
+	return uint(c - 2)
+}
+
+// multiMul computes the multi-scalar multiplication using the Pippenger's bucket method.
+// Scalars are split into windows of c bits, and all the windows are processed concurrently.
+// For each window the points are put into buckets according to the value of their digit,
+// and the buckets are summed up with appropriate weights using only additions.
+func multiMul(points []element, scalars []*big.Int) element {
+	reduced := make([]*big.Int, len(scalars))
+	maxBits := 0
+	for i, s := range scalars {
+		reduced[i] = new(big.Int).Mod(s, Order)
+		if reduced[i].BitLen() > maxBits {
+			maxBits = reduced[i].BitLen()
+		}
+	}
+	if maxBits == 0 {
+		return nil
+	}
+
+	c := windowSize(len(points))
+	nWindows := (maxBits + int(c) - 1) / int(c)
+	windows := make([]element, nWindows)
+
+	var wg sync.WaitGroup
+	for w := 0; w < nWindows; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			windows[w] = bucketSum(points, reduced, uint(w)*c, c)
+		}(w)
+	}
+	wg.Wait()
+
+	var result element
+	for w := nWindows - 1; w >= 0; w-- {
+		for i := uint(0); i < c; i++ {
+			result = add(result, result)
+		}
+		result = add(result, windows[w])
+	}
+	return result
+}
+
+// bucketSum returns the sum of points multiplied by the c-bit digits of scalars starting at the given bit.
+func bucketSum(points []element, scalars []*big.Int, start, c uint) element {
+	buckets := make([]element, (1<<c)-1)
+	for i, s := range scalars {
+		digit := 0
+		for j := c; j > 0; j-- {
+			digit = digit<<1 | int(s.Bit(int(start+j-1)))
+		}
+		if digit > 0 {
+			buckets[digit-1] = add(buckets[digit-1], points[i])
+		}
+	}
+	// sum of i*buckets[i-1] computed as the sum of suffix sums
+	var running, sum element
+	for i := len(buckets) - 1; i >= 0; i-- {
+		running = add(running, buckets[i])
+		sum = add(sum, running)
+	}
+	return sum
+}
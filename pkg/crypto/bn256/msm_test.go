@@ -0,0 +1,118 @@
+package bn256_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func randomScalars(n int) []*big.Int {
+	scalars := make([]*big.Int, n)
+	for i := range scalars {
+		scalars[i], _ = rand.Int(rand.Reader, Order)
+	}
+	return scalars
+}
+
+func randomKeys(n int) ([]*VerificationKey, []*Signature) {
+	vks := make([]*VerificationKey, n)
+	sgns := make([]*Signature, n)
+	for i := range vks {
+		vk, sk, _ := GenerateKeys()
+		vks[i] = vk
+		sgns[i] = sk.Sign([]byte("19890604"))
+	}
+	return vks, sgns
+}
+
+var _ = Describe("MultiMul", func() {
+	for _, n := range []int{1, 4, 33} {
+		n := n
+		Context(fmt.Sprintf("On %d elements", n), func() {
+			var (
+				vks     []*VerificationKey
+				sgns    []*Signature
+				scalars []*big.Int
+			)
+			BeforeEach(func() {
+				vks, sgns = randomKeys(n)
+				scalars = randomScalars(n)
+			})
+			It("Should agree with the naive computation for signatures", func() {
+				var expected *Signature
+				for i := range sgns {
+					expected = AddSignatures(expected, MulSignature(sgns[i], scalars[i]))
+				}
+				Expect(MultiMulSignatures(sgns, scalars).Marshal()).To(Equal(expected.Marshal()))
+			})
+			It("Should agree with the naive computation for verification keys", func() {
+				var expected *VerificationKey
+				for i := range vks {
+					expected = AddVerificationKeys(expected, MulVerificationKey(vks[i], scalars[i]))
+				}
+				Expect(MultiMulVerificationKeys(vks, scalars).Marshal()).To(Equal(expected.Marshal()))
+			})
+		})
+	}
+	Context("With scalars out of range", func() {
+		It("Should reduce them modulo the order", func() {
+			vks, sgns := randomKeys(3)
+			scalars := []*big.Int{big.NewInt(-1), new(big.Int).Add(Order, big.NewInt(2)), big.NewInt(0)}
+			reduced := []*big.Int{new(big.Int).Sub(Order, big.NewInt(1)), big.NewInt(2), big.NewInt(0)}
+			Expect(MultiMulSignatures(sgns, scalars).Marshal()).To(Equal(MultiMulSignatures(sgns, reduced).Marshal()))
+			Expect(MultiMulVerificationKeys(vks, scalars).Marshal()).To(Equal(MultiMulVerificationKeys(vks, reduced).Marshal()))
+		})
+	})
+	Context("With all scalars zero", func() {
+		It("Should return zero", func() {
+			vks, _ := randomKeys(2)
+			zeros := []*big.Int{big.NewInt(0), big.NewInt(0)}
+			Expect(MultiMulVerificationKeys(vks, zeros).Marshal()).To(Equal(NewVerificationKey(big.NewInt(0)).Marshal()))
+		})
+	})
+})
+
+var committeeSizes = []int{4, 10, 100, 1000}
+
+func BenchmarkMultiMulSignatures(b *testing.B) {
+	for _, n := range committeeSizes {
+		_, sgns := randomKeys(n)
+		scalars := randomScalars(n)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MultiMulSignatures(sgns, scalars)
+			}
+		})
+	}
+}
+
+func BenchmarkMultiMulVerificationKeys(b *testing.B) {
+	for _, n := range committeeSizes {
+		vks, _ := randomKeys(n)
+		scalars := randomScalars(n)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MultiMulVerificationKeys(vks, scalars)
+			}
+		})
+	}
+}
+
+func BenchmarkPolyVerify(b *testing.B) {
+	for _, n := range committeeSizes {
+		f := (n - 1) / 3
+		pv := NewPolyVerifier(n, f)
+		vks, _ := randomKeys(n)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pv.Verify(vks)
+			}
+		})
+	}
+}
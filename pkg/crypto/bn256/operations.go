@@ -43,6 +43,11 @@ func AddSignatures(sgn1, sgn2 *Signature) *Signature {
 	}
 }
 
+// NegSignature returns the negation of the provided signature, so that adding them gives zero.
+func NegSignature(sgn *Signature) *Signature {
+	return &Signature{*new(bn256.G1).Neg(&sgn.G1)}
+}
+
 // MulSignature returns the provided signature multiplied by the integer.
 // If the first argument is nil, it treats it as a one.
 func MulSignature(sgn *Signature, n *big.Int) *Signature {
@@ -55,6 +60,21 @@ func MulSignature(sgn *Signature, n *big.Int) *Signature {
 	}
 }
 
+// MulVerificationKey returns the provided verification key multiplied by the integer.
+func MulVerificationKey(vk *VerificationKey, n *big.Int) *VerificationKey {
+	return &VerificationKey{
+		key: *new(bn256.G2).ScalarMult(&vk.key, n),
+	}
+}
+
+// VerifyWithBase returns true if s is a valid signature of msg for vk, when base is used
+// in place of the generator of G2, i.e. if vk = x*base and s = x*hash(msg) for some x.
+func (vk *VerificationKey) VerifyWithBase(s *Signature, base *VerificationKey, msg []byte) bool {
+	p1 := bn256.Pair(&s.G1, &base.key).Marshal()
+	p2 := bn256.Pair(hash(msg), &vk.key).Marshal()
+	return subtle.ConstantTimeCompare(p1, p2) == 1
+}
+
 // VerifyKeys checks whether given secretKey and verificationKey forms a vaild pair.
 func VerifyKeys(vk *VerificationKey, sk *SecretKey) bool {
 	vk2 := sk.VerificationKey()
@@ -38,6 +38,13 @@ var _ = Describe("Operations", func() {
 				Expect(vk.Verify(sk.Sign(data), data)).To(BeTrue())
 			})
 		})
+		Context("Negation", func() {
+			It("Should cancel the signature when added to it", func() {
+				data := []byte("asdf")
+				sum := AddSignatures(AddSignatures(sk1.Sign(data), sk2.Sign(data)), NegSignature(sk2.Sign(data)))
+				Expect(sum.Marshal()).To(Equal(sk1.Sign(data).Marshal()))
+			})
+		})
 		Context("Multiplication", func() {
 			Context("Signature multiplied by one", func() {
 				It("Should be equal to the signature", func() {
@@ -3,8 +3,9 @@ package bn256
 import (
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding/binary"
+	"errors"
 	"math/big"
-	"sync"
 
 	"github.com/cloudflare/bn256"
 )
@@ -24,25 +25,7 @@ func (pv *PolyVerifier) Verify(elems []*VerificationKey) bool {
 	}
 
 	// computation of scalar product <pv.vector, elems>
-	scalarProduct := new(bn256.G2)
-	summands := make(chan *bn256.G2)
-
-	var wg sync.WaitGroup
-	for i, vk := range elems {
-		wg.Add(1)
-		go func(e *bn256.G2, i int) {
-			defer wg.Done()
-			summands <- new(bn256.G2).ScalarMult(e, pv.vector[i])
-		}(&vk.key, i)
-	}
-	go func() {
-		wg.Wait()
-		close(summands)
-	}()
-
-	for summand := range summands {
-		scalarProduct.Add(scalarProduct, summand)
-	}
+	scalarProduct := MultiMulVerificationKeys(elems, pv.vector)
 
 	// checking if the scalarProduct is the zero element of bn256.G2
 	zeroMarshalled := new(bn256.G2).Marshal()
@@ -50,89 +33,109 @@ func (pv *PolyVerifier) Verify(elems []*VerificationKey) bool {
 }
 
 // NewPolyVerifier returns a verifier of polynomial sequences
-// of degree at most f and length n.
+// of degree at most f and length n, evaluated at points 1,2,...,n.
 // We assume 0 <= f <= n-1.
 func NewPolyVerifier(n, f int) PolyVerifier {
-	// Here are some constants needed for computation of
-	// the inverse of the Vandermonde's matrix V(1,2,...,n)
-	// The constants depend only on n and should be big integers of length O(nlogn)
-	// newton[i][j] is the newton symbol (i choose j)
-	// sym[i][j] is the sum_{|S|=j, S in {1,2,...,i}} prod(S)
-	// coeff[i][j] is the sum_{S in {1,2,...,n}\{i}, |S|=j} prod(S)
-	newton := make([][]*big.Int, n+1)
-	for i := 0; i <= n; i++ {
-		newton[i] = make([]*big.Int, i+1)
-		newton[i][0] = big.NewInt(int64(1))
-		newton[i][i] = big.NewInt(int64(1))
-		for j := 1; j < i; j++ {
-			// newton[i][j] = newton[i-1][j] + newton[i-1][j-1]
-			newton[i][j] = big.NewInt(0).Add(newton[i-1][j], newton[i-1][j-1])
-		}
+	points := make([]*big.Int, n)
+	for i := range points {
+		points[i] = big.NewInt(int64(i + 1))
+	}
+	pv, _ := NewPolyVerifierAt(points, f)
+	return pv
+}
+
+// NewPolyVerifierAt returns a verifier of sequences of values of polynomials
+// of degree at most f at the given points. The points have to be distinct modulo Order.
+// We assume 0 <= f <= len(points)-1.
+//
+// The verifier is a random vector orthogonal to all such sequences.
+// It should be kept secret, since knowing it allows one to forge sequences that pass verification.
+func NewPolyVerifierAt(points []*big.Int, f int) (PolyVerifier, error) {
+	n := len(points)
+	xs := make([]*big.Int, n)
+	for i, p := range points {
+		xs[i] = new(big.Int).Mod(p, bn256.Order)
 	}
 
-	sym := make([][]*big.Int, n+1)
-	for i := 0; i <= n; i++ {
-		sym[i] = make([]*big.Int, i+1)
-		sym[i][0] = big.NewInt(int64(1))
-		for j := 1; j <= i; j++ {
-			// sym[i][j] = i*sym[i-1][j-1] + sym[i-1][j]
-			sym[i][j] = big.NewInt(int64(i))
-			sym[i][j] = sym[i][j].Mul(sym[i][j], sym[i-1][j-1])
-			if j <= i-1 {
-				sym[i][j] = sym[i][j].Add(sym[i][j], sym[i-1][j])
+	// weights[i] = 1 / prod_{j != i} (xs[i] - xs[j]) are the barycentric weights of the points.
+	// For any polynomial P of degree at most n-2 we have sum_i weights[i] * P(xs[i]) = 0.
+	weights := make([]*big.Int, n)
+	diff := new(big.Int)
+	for i := range xs {
+		prod := big.NewInt(1)
+		for j := range xs {
+			if i == j {
+				continue
 			}
+			diff.Sub(xs[i], xs[j])
+			prod.Mul(prod, diff)
+			prod.Mod(prod, bn256.Order)
+		}
+		if prod.Sign() == 0 {
+			return PolyVerifier{}, errors.New("evaluation points are not distinct")
 		}
+		weights[i] = prod.ModInverse(prod, bn256.Order)
 	}
 
-	coeff := make([][]*big.Int, n+1)
-	for i := 1; i <= n; i++ {
-		coeff[i] = make([]*big.Int, n)
-		coeff[i][0] = big.NewInt(int64(1))
-		for j := 1; j <= n-1; j++ {
-			// coeff[i][j] = sym[n][j] - i*coeff[i][j-1]
-			coeff[i][j] = big.NewInt(int64(-i))
-			coeff[i][j] = coeff[i][j].Mul(coeff[i][j], coeff[i][j-1])
-			coeff[i][j] = coeff[i][j].Add(coeff[i][j], sym[n][j])
+	// Our magic vector is weights multiplied pointwise by values of a random polynomial R
+	// of degree at most n-f-2, so that R*P has degree at most n-2 for every P of degree at most f.
+	coeffs := make([]*big.Int, n-f-1)
+	for i := range coeffs {
+		c, err := rand.Int(rand.Reader, bn256.Order)
+		if err != nil {
+			return PolyVerifier{}, err
 		}
+		coeffs[i] = c
 	}
-
-	// invV is the inverse of Vandermode's matrix V(1,2,...,n)
-	// after the following transformations
-	// (1) signs are ignored (all numbers are positive)
-	// (2) all numbers are multiplied by n!
-	// (3) rows are in reverse order
-	invV := make([][]*big.Int, n)
-	for i := 0; i < n; i++ {
-		invV[i] = make([]*big.Int, n)
-		for j := 0; j < n; j++ {
-			// invV[i][j] = newton[n-1][j] * coeff[j+1][i]
-			invV[i][j] = big.NewInt(int64(1))
-			invV[i][j] = invV[i][j].Mul(invV[i][j], newton[n-1][j])
-			invV[i][j] = invV[i][j].Mul(invV[i][j], coeff[j+1][i])
+	magicVector := make([]*big.Int, n)
+	for i, x := range xs {
+		value := big.NewInt(0)
+		for _, c := range coeffs {
+			value.Mul(value, x)
+			value.Add(value, c)
+			value.Mod(value, bn256.Order)
 		}
+		magicVector[i] = value.Mul(value, weights[i])
+		magicVector[i].Mod(magicVector[i], bn256.Order)
 	}
+	return PolyVerifier{vector: magicVector}, nil
+}
 
-	// Our magic vector is a random combination of last n-f-1 rows
-	// of the inverse of Vandermonde's matrix V(1,2,...,n)
-	magicVector := make([]*big.Int, n)
-	for i := 0; i < n; i++ {
-		magicVector[i] = big.NewInt(int64(0))
+// Marshal the verifier, so that it can be reused for the same evaluation points.
+// The result contains the secret vector and should be stored accordingly.
+func (pv *PolyVerifier) Marshal() []byte {
+	data := make([]byte, 4, 4+len(pv.vector)*scalarLength)
+	binary.LittleEndian.PutUint32(data, uint32(len(pv.vector)))
+	for _, v := range pv.vector {
+		data = append(data, marshalScalar(v)...)
 	}
-	for i := 0; i < n-f-1; i++ {
-		scalar, _ := rand.Int(rand.Reader, bn256.Order)
-		for j := 0; j < n; j++ {
-			term := big.NewInt(int64(1))
-			term.Mul(term, invV[i][j])
-			term.Mul(term, scalar)
-			if j%2 == 1 {
-				// the inverse of the V(1,2,...,n) has a checkboard sign pattern
-				term.Neg(term)
-			}
-			magicVector[j].Add(magicVector[j], term)
-		}
+	return data
+}
+
+// Unmarshal the verifier.
+func (pv *PolyVerifier) Unmarshal(data []byte) (*PolyVerifier, error) {
+	if len(data) < 4 {
+		return nil, errors.New("data too short")
 	}
-	for i := 0; i < n; i++ {
-		magicVector[i].Mod(magicVector[i], bn256.Order)
+	n := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	if len(data) != n*scalarLength {
+		return nil, errors.New("wrong data length")
+	}
+	vector := make([]*big.Int, n)
+	for i := range vector {
+		vector[i] = new(big.Int).SetBytes(data[i*scalarLength : (i+1)*scalarLength])
+		if vector[i].Cmp(bn256.Order) >= 0 {
+			return nil, errors.New("element of the vector out of range")
+		}
 	}
-	return PolyVerifier{vector: magicVector}
+	pv.vector = vector
+	return pv, nil
+}
+
+func marshalScalar(v *big.Int) []byte {
+	result := make([]byte, scalarLength)
+	b := v.Bytes()
+	copy(result[scalarLength-len(b):], b)
+	return result
 }
@@ -64,4 +64,42 @@ var _ = Describe("VeryPoly", func() {
 			})
 		})
 	})
+	Context("Poly Verifier at arbitrary points", func() {
+		var points []*big.Int
+		valuesAt := func(poly func(x int64) int64) []*VerificationKey {
+			result := make([]*VerificationKey, len(points))
+			for i, p := range points {
+				result[i] = NewVerificationKey(big.NewInt(poly(p.Int64())))
+			}
+			return result
+		}
+		BeforeEach(func() {
+			points = []*big.Int{big.NewInt(3), big.NewInt(7), big.NewInt(11), big.NewInt(20), big.NewInt(100), big.NewInt(101), big.NewInt(5)}
+			n, f = len(points), 2
+			var err error
+			pf, err = NewPolyVerifierAt(points, f)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should accept values of a polynomial of degree f", func() {
+			Expect(pf.Verify(valuesAt(func(x int64) int64 { return 5*x*x + 3*x + 1 }))).To(BeTrue())
+		})
+		It("should reject values of a polynomial of degree f+1", func() {
+			Expect(pf.Verify(valuesAt(func(x int64) int64 { return x*x*x + 1 }))).To(BeFalse())
+		})
+		It("should behave the same after marshaling and unmarshaling", func() {
+			pv2, err := new(PolyVerifier).Unmarshal(pf.Marshal())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pv2.Verify(valuesAt(func(x int64) int64 { return 2*x + 1 }))).To(BeTrue())
+			Expect(pv2.Verify(valuesAt(func(x int64) int64 { return x * x * x }))).To(BeFalse())
+		})
+		It("should return an error for repeated points", func() {
+			_, err := NewPolyVerifierAt(append(points, big.NewInt(7)), f)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail to unmarshal truncated data", func() {
+			data := pf.Marshal()
+			_, err := new(PolyVerifier).Unmarshal(data[:len(data)-1])
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,55 @@
+package bn256
+
+import (
+	"crypto/subtle"
+
+	"github.com/cloudflare/bn256"
+	"golang.org/x/crypto/sha3"
+)
+
+// VRFOutputLength is the length of outputs of the verifiable random function.
+const VRFOutputLength = 32
+
+// VRFProof proves that an output of the verifiable random function was computed correctly.
+// It is a signature of the input with a domain separate from regular signatures.
+// Since such signatures are unique, the output derived from the proof is unique as well.
+type VRFProof struct {
+	bn256.G1
+}
+
+// VRF returns the pseudorandom output for the input together with a proof of its correctness.
+func (sk *SecretKey) VRF(input []byte) ([]byte, *VRFProof) {
+	proof := &VRFProof{*new(bn256.G1).ScalarMult(hashVRF(input), &sk.key)}
+	return proof.Output(), proof
+}
+
+// VerifyVRF checks that the proof is valid for the input.
+// If it is, it also returns the output of the verifiable random function.
+func (vk *VerificationKey) VerifyVRF(input []byte, proof *VRFProof) ([]byte, bool) {
+	p1 := bn256.Pair(&proof.G1, gen).Marshal()
+	p2 := bn256.Pair(hashVRF(input), &vk.key).Marshal()
+	if subtle.ConstantTimeCompare(p1, p2) != 1 {
+		return nil, false
+	}
+	return proof.Output(), true
+}
+
+// Output returns the uniformly distributed output determined by the proof.
+// It should be used only after the proof has been verified.
+func (p *VRFProof) Output() []byte {
+	h := sha3.New256()
+	h.Write([]byte("az-vrf-output"))
+	h.Write(p.G1.Marshal())
+	return h.Sum(nil)
+}
+
+// Marshal the proof to bytes.
+func (p *VRFProof) Marshal() []byte {
+	return p.G1.Marshal()
+}
+
+// Unmarshal a proof from bytes.
+func (p *VRFProof) Unmarshal(data []byte) (*VRFProof, error) {
+	_, err := p.G1.Unmarshal(data)
+	return p, err
+}
@@ -0,0 +1,72 @@
+package bn256_test
+
+import (
+	. "gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VRF", func() {
+	var (
+		pub   *VerificationKey
+		priv  *SecretKey
+		input []byte
+	)
+	BeforeEach(func() {
+		var err error
+		pub, priv, err = GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		input = []byte("round 7")
+	})
+	Describe("When evaluated", func() {
+		var (
+			output []byte
+			proof  *VRFProof
+		)
+		BeforeEach(func() {
+			output, proof = priv.VRF(input)
+		})
+		It("should produce an output of the right length", func() {
+			Expect(output).To(HaveLen(VRFOutputLength))
+		})
+		It("should be deterministic", func() {
+			output2, proof2 := priv.VRF(input)
+			Expect(output2).To(Equal(output))
+			Expect(proof2.Marshal()).To(Equal(proof.Marshal()))
+		})
+		It("should be successfully verified", func() {
+			out, ok := pub.VerifyVRF(input, proof)
+			Expect(ok).To(BeTrue())
+			Expect(out).To(Equal(output))
+		})
+		It("should be successfully verified after marshaling and unmarshaling the proof", func() {
+			p, err := new(VRFProof).Unmarshal(proof.Marshal())
+			Expect(err).NotTo(HaveOccurred())
+			out, ok := pub.VerifyVRF(input, p)
+			Expect(ok).To(BeTrue())
+			Expect(out).To(Equal(output))
+		})
+		It("should fail for a different input", func() {
+			_, ok := pub.VerifyVRF([]byte("round 8"), proof)
+			Expect(ok).To(BeFalse())
+		})
+		It("should fail for a different key", func() {
+			pub2, _, err := GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := pub2.VerifyVRF(input, proof)
+			Expect(ok).To(BeFalse())
+		})
+		It("should give different outputs for different inputs", func() {
+			output2, _ := priv.VRF([]byte("round 8"))
+			Expect(output2).NotTo(Equal(output))
+		})
+		It("should not be usable as a regular signature", func() {
+			Expect(pub.Verify(&Signature{proof.G1}, input)).To(BeFalse())
+		})
+		It("should not accept a regular signature as a proof", func() {
+			_, ok := pub.VerifyVRF(input, &VRFProof{priv.Sign(input).G1})
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
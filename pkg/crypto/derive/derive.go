@@ -0,0 +1,137 @@
+// Package derive implements deterministic derivation of all keys of a committee member from a single master seed.
+//
+// Keys are derived along paths of the form m/<key type>/<epoch>. Every node of the path
+// is a seed obtained from its parent using HMAC-SHA512, so the master seed is the only
+// secret that needs a backup. Keys for a new epoch can be derived at any time,
+// and keys of past epochs can be recreated after losing them.
+package derive
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+)
+
+// SeedLength is the length of seeds in bytes.
+const SeedLength = 32
+
+// KeyType identifies the branch of the derivation tree used for a kind of keys.
+type KeyType string
+
+const (
+	// BN256 keys are used for signing.
+	BN256 KeyType = "bn256"
+	// P2P keys are used for deriving pairwise symmetric keys.
+	P2P KeyType = "p2p"
+	// RSA keys are used for encryption.
+	RSA KeyType = "rsa"
+)
+
+// Seed is a node of the derivation tree.
+type Seed []byte
+
+// NewSeed returns a random master seed.
+func NewSeed() (Seed, error) {
+	seed := make([]byte, SeedLength)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// Encode encodes the seed into a base64 string.
+func (s Seed) Encode() string {
+	return base64.StdEncoding.EncodeToString(s)
+}
+
+// DecodeSeed decodes a seed encoded as a base64 string.
+func DecodeSeed(enc string) (Seed, error) {
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != SeedLength {
+		return nil, errors.New("wrong length of seed")
+	}
+	return data, nil
+}
+
+// Child returns the seed derived from s for the given label.
+func (s Seed) Child(label []byte) Seed {
+	mac := hmac.New(sha512.New, s)
+	mac.Write(label)
+	return mac.Sum(nil)[:SeedLength]
+}
+
+// Path returns the seed for keys of the given type in the given epoch.
+func (s Seed) Path(kt KeyType, epoch uint32) Seed {
+	epochBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(epochBytes, epoch)
+	return s.Child([]byte(kt)).Child(epochBytes)
+}
+
+// stream returns an infinite deterministic stream of bytes based on the seed.
+func (s Seed) stream() io.Reader {
+	h := sha3.NewShake256()
+	h.Write(s)
+	return h
+}
+
+// scalar returns a number in [0, bn256.Order) based on the seed.
+func (s Seed) scalar() *big.Int {
+	buf := make([]byte, 64)
+	s.stream().Read(buf)
+	return new(big.Int).Mod(new(big.Int).SetBytes(buf), bn256.Order)
+}
+
+// BN256 returns the bn256 keys for the given epoch.
+func (s Seed) BN256(epoch uint32) (*bn256.VerificationKey, *bn256.SecretKey) {
+	sk := bn256.NewSecretKey(s.Path(BN256, epoch).scalar())
+	return sk.VerificationKey(), sk
+}
+
+// P2P returns the p2p keys for the given epoch.
+func (s Seed) P2P(epoch uint32) (*p2p.PublicKey, *p2p.SecretKey) {
+	sk := p2p.NewSecretKey(s.Path(P2P, epoch).scalar())
+	return sk.PublicKey(), sk
+}
+
+// RSA returns the RSA keys for the given epoch.
+func (s Seed) RSA(epoch uint32) (encrypt.EncryptionKey, encrypt.DecryptionKey, error) {
+	return encrypt.GenerateKeysFrom(s.Path(RSA, epoch).stream())
+}
+
+// Keys contains all the keys of a committee member in one epoch.
+type Keys struct {
+	Epoch  uint32
+	VerKey *bn256.VerificationKey
+	SigKey *bn256.SecretKey
+	P2PPub *p2p.PublicKey
+	P2PSec *p2p.SecretKey
+	EncKey encrypt.EncryptionKey
+	DecKey encrypt.DecryptionKey
+}
+
+// Keys returns all the keys for the given epoch.
+func (s Seed) Keys(epoch uint32) (*Keys, error) {
+	keys := &Keys{Epoch: epoch}
+	keys.VerKey, keys.SigKey = s.BN256(epoch)
+	keys.P2PPub, keys.P2PSec = s.P2P(epoch)
+	var err error
+	keys.EncKey, keys.DecKey, err = s.RSA(epoch)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,13 @@
+package derive_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDerive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Derive Suite")
+}
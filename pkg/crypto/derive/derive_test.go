@@ -0,0 +1,63 @@
+package derive_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/derive"
+)
+
+var _ = Describe("Derive", func() {
+	var seed Seed
+	BeforeEach(func() {
+		var err error
+		seed, err = NewSeed()
+		Expect(err).NotTo(HaveOccurred())
+	})
+	Context("Keys derived twice from the same seed", func() {
+		It("Should be the same", func() {
+			restored, err := DecodeSeed(seed.Encode())
+			Expect(err).NotTo(HaveOccurred())
+			keys1, err := seed.Keys(7)
+			Expect(err).NotTo(HaveOccurred())
+			keys2, err := restored.Keys(7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys1.VerKey.Marshal()).To(Equal(keys2.VerKey.Marshal()))
+			Expect(keys1.SigKey.Marshal()).To(Equal(keys2.SigKey.Marshal()))
+			Expect(keys1.P2PPub.Marshal()).To(Equal(keys2.P2PPub.Marshal()))
+			Expect(keys1.P2PSec.Marshal()).To(Equal(keys2.P2PSec.Marshal()))
+			Expect(keys1.EncKey.Encode()).To(Equal(keys2.EncKey.Encode()))
+			Expect(keys1.DecKey.Encode()).To(Equal(keys2.DecKey.Encode()))
+		})
+	})
+	Context("Keys derived for different epochs", func() {
+		It("Should differ", func() {
+			vk1, _ := seed.BN256(0)
+			vk2, _ := seed.BN256(1)
+			Expect(vk1.Marshal()).NotTo(Equal(vk2.Marshal()))
+			pk1, _ := seed.P2P(0)
+			pk2, _ := seed.P2P(1)
+			Expect(pk1.Marshal()).NotTo(Equal(pk2.Marshal()))
+		})
+	})
+	Context("Derived keys", func() {
+		It("Should form valid pairs", func() {
+			vk, sk := seed.BN256(3)
+			data := []byte("19890604")
+			Expect(vk.Verify(sk.Sign(data), data)).To(BeTrue())
+			pk, _ := seed.P2P(3)
+			Expect(pk.Verify()).To(BeTrue())
+			ek, dk, err := seed.RSA(3)
+			Expect(err).NotTo(HaveOccurred())
+			ct, err := ek.Encrypt(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dk.Decrypt(ct)).To(Equal(data))
+		})
+	})
+	Context("Decoding a seed of wrong length", func() {
+		It("Should return an error", func() {
+			_, err := DecodeSeed("AAAA")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -33,19 +33,27 @@ func NewSymmetricKey(key []byte) (SymmetricKey, error) {
 }
 
 func (sk *symmetricKey) Encrypt(msg []byte) (CipherText, error) {
+	return sk.EncryptWithData(msg, nil)
+}
+
+func (sk *symmetricKey) Decrypt(ct CipherText) ([]byte, error) {
+	return sk.DecryptWithData(ct, nil)
+}
+
+func (sk *symmetricKey) EncryptWithData(msg, ad []byte) (CipherText, error) {
 	nonce := make([]byte, sk.gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
-	return sk.gcm.Seal(nonce, nonce, msg, nil), nil
+	return sk.gcm.Seal(nonce, nonce, msg, ad), nil
 }
 
-func (sk *symmetricKey) Decrypt(ct CipherText) ([]byte, error) {
+func (sk *symmetricKey) DecryptWithData(ct CipherText, ad []byte) ([]byte, error) {
 	nonceSize := sk.gcm.NonceSize()
 	if len(ct) < nonceSize {
 		return nil, errors.New("ciphertext too short")
 	}
 	nonce, ct := ct[:nonceSize], ct[nonceSize:]
 
-	return sk.gcm.Open(nil, nonce, ct, nil)
+	return sk.gcm.Open(nil, nonce, ct, ad)
 }
@@ -41,6 +41,18 @@ var _ = Describe("Encryption", func() {
 				Expect(bytes.Equal(msg, dmsg)).To(BeTrue())
 			})
 
+			It("Should bind ciphertexts to the associated data", func() {
+				ct, err = sk.EncryptWithData(msg, []byte("ad"))
+				Expect(err).To(BeNil())
+				dmsg, err := sk.DecryptWithData(ct, []byte("ad"))
+				Expect(err).To(BeNil())
+				Expect(bytes.Equal(msg, dmsg)).To(BeTrue())
+				_, err = sk.DecryptWithData(ct, []byte("da"))
+				Expect(err).NotTo(BeNil())
+				_, err = sk.Decrypt(ct)
+				Expect(err).NotTo(BeNil())
+			})
+
 			It("Should return false for forged ciphertext", func() {
 				ct[0]++
 				dmsg, err := sk.Decrypt(ct)
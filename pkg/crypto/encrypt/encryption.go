@@ -33,4 +33,9 @@ type SymmetricKey interface {
 	Encrypt([]byte) (CipherText, error)
 	// Decrypt decrypts ciphertext that was encrypted with the key.
 	Decrypt(CipherText) ([]byte, error)
+	// EncryptWithData encrypts message and binds the ciphertext to the associated data,
+	// e.g. identities of the sender and the recipient. The associated data is authenticated but not encrypted.
+	EncryptWithData(msg, ad []byte) (CipherText, error)
+	// DecryptWithData decrypts ciphertext that was encrypted with the key and the same associated data.
+	DecryptWithData(ct CipherText, ad []byte) ([]byte, error)
 }
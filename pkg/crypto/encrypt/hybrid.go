@@ -0,0 +1,110 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// hybridKeyLength is the length of the fresh symmetric keys wrapped with RSA.
+const hybridKeyLength = 32
+
+var hybridLabel = []byte("encrypt-hybrid")
+
+// hybridEncryptionKey implements EncryptionKey for messages of arbitrary size.
+type hybridEncryptionKey struct {
+	encryptionKey
+}
+
+// hybridDecryptionKey implements DecryptionKey for ciphertexts of hybridEncryptionKey.
+// It is interchangeable with the wrapped key, which decrypts hybrid ciphertexts as well.
+type hybridDecryptionKey struct {
+	decryptionKey
+}
+
+// NewHybridEncryptionKey returns an encryption key that encrypts messages of any size with the given RSA key.
+// Every message is encrypted with a fresh symmetric key, which is in turn encrypted with RSA-OAEP.
+// The ciphertexts are of the form
+// (1) the symmetric key encrypted with RSA-OAEP, of the length of the RSA modulus
+// (2) the message encrypted with the symmetric key, with (1) as associated data
+// Encode returns the same representation as the wrapped key, and decryption keys decoded from the matching
+// representation decrypt the hybrid ciphertexts, since they are longer than the RSA modulus unlike plain RSA-OAEP ones.
+func NewHybridEncryptionKey(ek EncryptionKey) (EncryptionKey, error) {
+	rsaKey, ok := rsaEncryptionKey(ek)
+	if !ok {
+		return nil, errors.New("unsupported encryption key")
+	}
+	return &hybridEncryptionKey{*rsaKey}, nil
+}
+
+// NewHybridDecryptionKey returns a decryption key for ciphertexts created by NewHybridEncryptionKey
+// with the matching encryption key. Encode returns the same representation as the wrapped key.
+func NewHybridDecryptionKey(dk DecryptionKey) (DecryptionKey, error) {
+	rsaKey, ok := rsaDecryptionKey(dk)
+	if !ok {
+		return nil, errors.New("unsupported decryption key")
+	}
+	return &hybridDecryptionKey{*rsaKey}, nil
+}
+
+func (ek *hybridEncryptionKey) Encrypt(msg []byte) (CipherText, error) {
+	key := make([]byte, hybridKeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, ek.encKey, key, hybridLabel)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := NewSymmetricKey(key)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := sk.EncryptWithData(msg, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return append(wrapped, ct...), nil
+}
+
+// decryptHybrid decrypts a ciphertext of hybridEncryptionKey.
+func decryptHybrid(decKey *rsa.PrivateKey, ct CipherText) ([]byte, error) {
+	size := decKey.Size()
+	if len(ct) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+	wrapped := ct[:size]
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, decKey, wrapped, hybridLabel)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := NewSymmetricKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return sk.DecryptWithData(ct[size:], wrapped)
+}
+
+// rsaEncryptionKey returns the RSA key of the given encryption key, unwrapping hybrid keys.
+func rsaEncryptionKey(ek EncryptionKey) (*encryptionKey, bool) {
+	switch key := ek.(type) {
+	case *encryptionKey:
+		return key, true
+	case *hybridEncryptionKey:
+		return &key.encryptionKey, true
+	}
+	return nil, false
+}
+
+// rsaDecryptionKey returns the RSA key of the given decryption key, unwrapping hybrid keys.
+func rsaDecryptionKey(dk DecryptionKey) (*decryptionKey, bool) {
+	switch key := dk.(type) {
+	case *decryptionKey:
+		return key, true
+	case *hybridDecryptionKey:
+		return &key.decryptionKey, true
+	}
+	return nil, false
+}
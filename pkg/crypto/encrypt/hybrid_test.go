@@ -0,0 +1,91 @@
+package encrypt_test
+
+import (
+	"crypto/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+var _ = Describe("Hybrid encryption", func() {
+	var (
+		ek, hek EncryptionKey
+		dk, hdk DecryptionKey
+		msg     []byte
+	)
+	BeforeEach(func() {
+		var err error
+		ek, dk, err = GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		hek, err = NewHybridEncryptionKey(ek)
+		Expect(err).NotTo(HaveOccurred())
+		hdk, err = NewHybridDecryptionKey(dk)
+		Expect(err).NotTo(HaveOccurred())
+		msg = make([]byte, 10000)
+		rand.Read(msg)
+	})
+	It("Should encrypt and decrypt large messages", func() {
+		ct, err := hek.Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := hdk.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+	})
+	It("Should be refused by plain RSA-OAEP with a clear error", func() {
+		_, err := ek.Encrypt(msg)
+		Expect(err).To(MatchError(ContainSubstring("hybrid")))
+	})
+	It("Should reject modified ciphertexts", func() {
+		ct, _ := hek.Encrypt(msg)
+		ct[len(ct)-1] ^= 1
+		_, err := hdk.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+		ct[len(ct)-1] ^= 1
+		ct[0] ^= 1
+		_, err = hdk.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+		_, err = hdk.Decrypt(ct[:100])
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should keep the encoding and the matching encryption key of the wrapped keys", func() {
+		Expect(hek.Encode()).To(Equal(ek.Encode()))
+		Expect(hdk.Encode()).To(Equal(dk.Encode()))
+		matching, err := EncryptionKeyOf(hdk)
+		Expect(err).NotTo(HaveOccurred())
+		ct, err := matching.Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := hdk.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+	})
+	It("Should be decrypted by keys decoded from the encoding of the hybrid key", func() {
+		ct, err := hek.Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		decoded, err := NewDecryptionKey(hdk.Encode())
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := decoded.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+		small := []byte("19890604")
+		ct, err = ek.Encrypt(small)
+		Expect(err).NotTo(HaveOccurred())
+		dec, err = hdk.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(small))
+	})
+	It("Should be encoded as PEM like the wrapped keys", func() {
+		pub, err := EncodeEncryptionKeyPEM(hek)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(EncodeEncryptionKeyPEM(ek)).To(Equal(pub))
+		priv, err := EncodeDecryptionKeyPEM(hdk, nil)
+		Expect(err).NotTo(HaveOccurred())
+		decoded, err := NewDecryptionKey(priv)
+		Expect(err).NotTo(HaveOccurred())
+		ct, _ := hek.Encrypt(msg)
+		dec, err := decoded.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+	})
+})
@@ -0,0 +1,268 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	pemPublicKey           = "PUBLIC KEY"
+	pemRSAPublicKey        = "RSA PUBLIC KEY"
+	pemPrivateKey          = "PRIVATE KEY"
+	pemRSAPrivateKey       = "RSA PRIVATE KEY"
+	pemEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+
+	// pbkdf2Iterations is the iteration count of PBKDF2-HMAC-SHA256, as recommended by OWASP.
+	pbkdf2Iterations = 600000
+	pbkdf2SaltLength = 16
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// EncodeEncryptionKeyPEM encodes the encryption key as a PEM block with a PKIX public key.
+// Hybrid keys are encoded as the RSA keys they wrap.
+func EncodeEncryptionKeyPEM(ek EncryptionKey) (string, error) {
+	rsaKey, ok := rsaEncryptionKey(ek)
+	if !ok {
+		return "", errors.New("unsupported encryption key")
+	}
+	der, err := x509.MarshalPKIXPublicKey(rsaKey.encKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: der})), nil
+}
+
+// EncodeDecryptionKeyPEM encodes the decryption key as a PEM block with a PKCS#8 private key.
+// If the passphrase is not empty, the key is encrypted with PBES2, using PBKDF2-HMAC-SHA256 and AES-256-CBC.
+func EncodeDecryptionKeyPEM(dk DecryptionKey, passphrase []byte) (string, error) {
+	rsaKey, ok := rsaDecryptionKey(dk)
+	if !ok {
+		return "", errors.New("unsupported decryption key")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey.decKey)
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return string(pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: der})), nil
+	}
+	der, err = encryptPKCS8(der, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemEncryptedPrivateKey, Bytes: der})), nil
+}
+
+// NewDecryptionKeyWithPassphrase creates a decryptionKey from its representation, like NewDecryptionKey,
+// using the passphrase if the key is an encrypted PKCS#8 private key.
+func NewDecryptionKeyWithPassphrase(text string, passphrase []byte) (DecryptionKey, error) {
+	if !isPEM(text) {
+		return newLegacyDecryptionKey(text)
+	}
+	block, err := decodePEM(text)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case pemPrivateKey:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case pemRSAPrivateKey:
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pemEncryptedPrivateKey:
+		if len(passphrase) == 0 {
+			return nil, errors.New("passphrase required for an encrypted private key")
+		}
+		var der []byte
+		der, err = decryptPKCS8(block.Bytes, passphrase)
+		if err == nil {
+			key, err = x509.ParsePKCS8PrivateKey(der)
+		}
+	default:
+		return nil, errors.New("unsupported PEM block type " + block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	if err := rsaKey.Validate(); err != nil {
+		return nil, err
+	}
+	return &decryptionKey{rsaKey}, nil
+}
+
+func newPEMEncryptionKey(text string) (EncryptionKey, error) {
+	block, err := decodePEM(text)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case pemPublicKey:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("not an RSA public key")
+		}
+		return &encryptionKey{rsaKey}, nil
+	case pemRSAPublicKey:
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &encryptionKey{key}, nil
+	}
+	return nil, errors.New("unsupported PEM block type " + block.Type)
+}
+
+func isPEM(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "-----BEGIN ")
+}
+
+func decodePEM(text string) (*pem.Block, error) {
+	block, rest := pem.Decode([]byte(strings.TrimSpace(text)))
+	if block == nil {
+		return nil, errors.New("malformed PEM block")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("trailing data after PEM block")
+	}
+	return block, nil
+}
+
+// encryptedPrivateKeyInfo is the ASN.1 structure of an encrypted PKCS#8 private key (RFC 5208),
+// restricted to the PBES2 scheme (RFC 8018).
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pbes2Algorithm
+	EncryptedData []byte
+}
+
+type pbes2Algorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters pbes2Params
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pbkdf2Algorithm
+	EncryptionScheme  encryptionScheme
+}
+
+type pbkdf2Algorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters pbkdf2Params
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type encryptionScheme struct {
+	Algorithm asn1.ObjectIdentifier
+	IV        []byte
+}
+
+func encryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, pbkdf2SaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	data := append(append([]byte{}, der...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pbes2Algorithm{
+			Algorithm: oidPBES2,
+			Parameters: pbes2Params{
+				KeyDerivationFunc: pbkdf2Algorithm{
+					Algorithm: oidPBKDF2,
+					Parameters: pbkdf2Params{
+						Salt:           salt,
+						IterationCount: pbkdf2Iterations,
+						PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+					},
+				},
+				EncryptionScheme: encryptionScheme{Algorithm: oidAES256CBC, IV: iv},
+			},
+		},
+		EncryptedData: data,
+	})
+}
+
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after encrypted private key")
+	}
+	params := info.Algorithm.Parameters
+	kdf := params.KeyDerivationFunc.Parameters
+	switch {
+	case !info.Algorithm.Algorithm.Equal(oidPBES2):
+		return nil, errors.New("unsupported encryption of private key")
+	case !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2):
+		return nil, errors.New("unsupported key derivation function")
+	case !kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		return nil, errors.New("unsupported pseudorandom function")
+	case kdf.KeyLength != 0 && kdf.KeyLength != 32:
+		return nil, errors.New("wrong key length")
+	case kdf.IterationCount <= 0 || kdf.IterationCount > 10*pbkdf2Iterations:
+		return nil, errors.New("wrong iteration count")
+	case !params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		return nil, errors.New("unsupported encryption scheme")
+	case len(params.EncryptionScheme.IV) != aes.BlockSize:
+		return nil, errors.New("wrong initialization vector")
+	}
+	data := info.EncryptedData
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("wrong length of encrypted private key")
+	}
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, params.EncryptionScheme.IV).CryptBlocks(plain, data)
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("wrong passphrase")
+	}
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,151 @@
+package encrypt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+)
+
+// DefaultRotationLimit is the number of messages encrypted with a single key after which it is rotated.
+// It keeps the probability of a collision of random 96-bit nonces under a single key negligible.
+const DefaultRotationLimit = 1 << 30
+
+// RotatingKey is a SymmetricKey deriving a fresh key every time the current one has been used too many times.
+type RotatingKey interface {
+	SymmetricKey
+	// Epoch returns the epoch of the key used by the latest encryption.
+	Epoch() uint32
+}
+
+// rotatingKey implements RotatingKey. Messages sent in each direction are encrypted with different keys.
+type rotatingKey struct {
+	sync.Mutex
+	base   []byte
+	self   uint16
+	peer   uint16
+	limit  uint64
+	epoch  uint32
+	count  uint64
+	encKey *epochKey
+	decKey *epochKey
+}
+
+// epochKey is a key derived for a single epoch.
+type epochKey struct {
+	epoch uint32
+	key   SymmetricKey
+}
+
+// NewRotatingKey creates a symmetric key shared by the members self and peer, that is rotated after encrypting limit messages.
+// The keys used by self and peer for encryption are derived separately from the given one, so ciphertexts of one member
+// cannot be reflected back to it. Encryption starts with the key of the given epoch. The counters are kept in memory only,
+// so after a restart the key has to be created with an epoch later than any used before, e.g. the persisted Epoch plus one.
+// The ciphertexts are of the form
+// (1) epoch of the key used, 4 bytes as uint32
+// (2) ciphertext created with the key of that epoch, with the epoch included in the associated data.
+func NewRotatingKey(key []byte, self, peer uint16, epoch uint32, limit uint64) (RotatingKey, error) {
+	if limit == 0 {
+		return nil, errors.New("rotation limit has to be positive")
+	}
+	if self == peer {
+		return nil, errors.New("rotating key has to be shared by two members")
+	}
+	return &rotatingKey{
+		base:  append([]byte{}, key...),
+		self:  self,
+		peer:  peer,
+		limit: limit,
+		epoch: epoch,
+	}, nil
+}
+
+func (rk *rotatingKey) Epoch() uint32 {
+	rk.Lock()
+	defer rk.Unlock()
+	return rk.epoch
+}
+
+func (rk *rotatingKey) Encrypt(msg []byte) (CipherText, error) {
+	return rk.EncryptWithData(msg, nil)
+}
+
+func (rk *rotatingKey) Decrypt(ct CipherText) ([]byte, error) {
+	return rk.DecryptWithData(ct, nil)
+}
+
+func (rk *rotatingKey) EncryptWithData(msg, ad []byte) (CipherText, error) {
+	epoch, key, err := rk.use()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint32(header, epoch)
+	ct, err := key.EncryptWithData(msg, append(append([]byte{}, header...), ad...))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ct...), nil
+}
+
+func (rk *rotatingKey) DecryptWithData(ct CipherText, ad []byte) ([]byte, error) {
+	if len(ct) < 4 {
+		return nil, errors.New("ciphertext too short")
+	}
+	key, err := rk.peerKey(binary.LittleEndian.Uint32(ct))
+	if err != nil {
+		return nil, err
+	}
+	return key.DecryptWithData(ct[4:], append(append([]byte{}, ct[:4]...), ad...))
+}
+
+// use counts a single encryption and returns the epoch and the key to use for it.
+func (rk *rotatingKey) use() (uint32, SymmetricKey, error) {
+	rk.Lock()
+	defer rk.Unlock()
+	if rk.count >= rk.limit {
+		if rk.epoch == math.MaxUint32 {
+			return 0, nil, errors.New("all the keys are used up")
+		}
+		rk.epoch++
+		rk.count = 0
+	}
+	if rk.encKey == nil || rk.encKey.epoch != rk.epoch {
+		key, err := rk.derive(rk.self, rk.peer, rk.epoch)
+		if err != nil {
+			return 0, nil, err
+		}
+		rk.encKey = &epochKey{rk.epoch, key}
+	}
+	rk.count++
+	return rk.epoch, rk.encKey.key, nil
+}
+
+// peerKey returns the key used by the peer in the given epoch, caching the latest one.
+func (rk *rotatingKey) peerKey(epoch uint32) (SymmetricKey, error) {
+	rk.Lock()
+	defer rk.Unlock()
+	if rk.decKey != nil && rk.decKey.epoch == epoch {
+		return rk.decKey.key, nil
+	}
+	key, err := rk.derive(rk.peer, rk.self, epoch)
+	if err != nil {
+		return nil, err
+	}
+	rk.decKey = &epochKey{epoch, key}
+	return key, nil
+}
+
+// derive derives the key used by sender to encrypt messages for receiver in the given epoch.
+func (rk *rotatingKey) derive(sender, receiver uint16, epoch uint32) (SymmetricKey, error) {
+	data := make([]byte, 0, len(rotationLabel)+len(rk.base)+8)
+	data = append(data, rotationLabel...)
+	data = append(data, rk.base...)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint16(buf, sender)
+	binary.LittleEndian.PutUint16(buf[2:], receiver)
+	binary.LittleEndian.PutUint32(buf[4:], epoch)
+	return NewSymmetricKey(append(data, buf...))
+}
+
+const rotationLabel = "encrypt-rotation"
@@ -0,0 +1,79 @@
+package encrypt_test
+
+import (
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+var _ = Describe("Rotating key", func() {
+	var (
+		rk  RotatingKey
+		msg []byte
+	)
+	BeforeEach(func() {
+		var err error
+		rk, err = NewRotatingKey([]byte("2137"), 0, 1, 0, 2)
+		Expect(err).NotTo(HaveOccurred())
+		msg = []byte("19890604")
+	})
+	It("Should rotate the key after the limit and decrypt old ciphertexts", func() {
+		cts := make([]CipherText, 5)
+		for i := range cts {
+			var err error
+			cts[i], err = rk.Encrypt(msg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(binary.LittleEndian.Uint32(cts[i])).To(Equal(uint32(i / 2)))
+		}
+		Expect(rk.Epoch()).To(Equal(uint32(2)))
+		other, _ := NewRotatingKey([]byte("2137"), 1, 0, 0, DefaultRotationLimit)
+		for _, ct := range cts {
+			dec, err := other.Decrypt(ct)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dec).To(Equal(msg))
+		}
+	})
+	It("Should use different keys in different epochs", func() {
+		ct, _ := rk.Encrypt(msg)
+		binary.LittleEndian.PutUint32(ct, 1)
+		other, _ := NewRotatingKey([]byte("2137"), 1, 0, 0, DefaultRotationLimit)
+		_, err := other.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should use different keys in different directions", func() {
+		ct, _ := rk.Encrypt(msg)
+		_, err := rk.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should start from the given epoch", func() {
+		restarted, err := NewRotatingKey([]byte("2137"), 0, 1, 7, 2)
+		Expect(err).NotTo(HaveOccurred())
+		ct, err := restarted.Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binary.LittleEndian.Uint32(ct)).To(Equal(uint32(7)))
+		Expect(restarted.Epoch()).To(Equal(uint32(7)))
+	})
+	It("Should bind ciphertexts to the associated data", func() {
+		ct, err := rk.EncryptWithData(msg, []byte("dealer 1, owner 2"))
+		Expect(err).NotTo(HaveOccurred())
+		other, _ := NewRotatingKey([]byte("2137"), 1, 0, 0, DefaultRotationLimit)
+		dec, err := other.DecryptWithData(ct, []byte("dealer 1, owner 2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+		_, err = other.DecryptWithData(ct, []byte("dealer 2, owner 1"))
+		Expect(err).To(HaveOccurred())
+		_, err = other.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should not be created with a zero limit", func() {
+		_, err := NewRotatingKey([]byte("2137"), 0, 1, 0, 0)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should not be created for a single member", func() {
+		_, err := NewRotatingKey([]byte("2137"), 1, 1, 0, 2)
+		Expect(err).To(HaveOccurred())
+	})
+})
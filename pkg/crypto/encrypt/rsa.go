@@ -5,6 +5,7 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"errors"
+	"io"
 	"math/big"
 	"strconv"
 	"strings"
@@ -29,11 +30,87 @@ func GenerateKeys() (EncryptionKey, DecryptionKey, error) {
 	return &encryptionKey{&privKey.PublicKey}, &decryptionKey{privKey}, nil
 }
 
+// GenerateKeysFrom deterministically creates a pair of keys for encryption/decryption
+// using randomness read from rnd. The same stream of bytes always results in the same keys.
+func GenerateKeysFrom(rnd io.Reader) (EncryptionKey, DecryptionKey, error) {
+	const e = 65537
+	for {
+		p, err := generatePrime(rnd, 1024)
+		if err != nil {
+			return nil, nil, err
+		}
+		q, err := generatePrime(rnd, 1024)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		pMinus1 := new(big.Int).Sub(p, big.NewInt(1))
+		qMinus1 := new(big.Int).Sub(q, big.NewInt(1))
+		phi := new(big.Int).Mul(pMinus1, qMinus1)
+		d := new(big.Int).ModInverse(big.NewInt(e), phi)
+		if d == nil {
+			continue
+		}
+		privKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: e},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := privKey.Validate(); err != nil {
+			return nil, nil, err
+		}
+		privKey.Precompute()
+		return &encryptionKey{&privKey.PublicKey}, &decryptionKey{privKey}, nil
+	}
+}
+
+// generatePrime finds a prime with the given number of bits by searching upwards from a starting point read from rnd.
+// Two highest bits are set, so that a product of two such primes has exactly 2*bits bits.
+func generatePrime(rnd io.Reader, bits int) (*big.Int, error) {
+	buf := make([]byte, bits/8)
+	if _, err := io.ReadFull(rnd, buf); err != nil {
+		return nil, err
+	}
+	buf[0] |= 0xc0
+	buf[len(buf)-1] |= 1
+	p := new(big.Int).SetBytes(buf)
+	two := big.NewInt(2)
+	for !p.ProbablyPrime(20) {
+		p.Add(p, two)
+	}
+	if p.BitLen() != bits {
+		return generatePrime(rnd, bits)
+	}
+	return p, nil
+}
+
+// EncryptionKeyOf returns the encryption key matching the given decryption key.
+func EncryptionKeyOf(dk DecryptionKey) (EncryptionKey, error) {
+	if hybridKey, ok := dk.(*hybridDecryptionKey); ok {
+		return &hybridEncryptionKey{encryptionKey{&hybridKey.decKey.PublicKey}}, nil
+	}
+	rsaKey, ok := dk.(*decryptionKey)
+	if !ok {
+		return nil, errors.New("unsupported decryption key")
+	}
+	return &encryptionKey{&rsaKey.decKey.PublicKey}, nil
+}
+
 func (ek *encryptionKey) Encrypt(msg []byte) (CipherText, error) {
+	if len(msg) > ek.encKey.Size()-2*sha256.Size-2 {
+		return nil, errors.New("message too long for RSA-OAEP, use a hybrid encryption key")
+	}
 	return rsa.EncryptOAEP(sha256.New(), rand.Reader, ek.encKey, msg, nil)
 }
 
+// Decrypt decrypts ciphertexts of both encryptionKey and hybridEncryptionKey.
+// Ciphertexts of RSA-OAEP are exactly as long as the modulus, while the hybrid ones are longer.
 func (dk *decryptionKey) Decrypt(ct CipherText) ([]byte, error) {
+	if len(ct) > dk.decKey.Size() {
+		return decryptHybrid(dk.decKey, ct)
+	}
 	return rsa.DecryptOAEP(sha256.New(), rand.Reader, dk.decKey, ct, nil)
 }
 
@@ -41,8 +118,12 @@ func (ek *encryptionKey) Encode() string {
 	return ek.encKey.N.Text(big.MaxBase) + "#" + strconv.Itoa(ek.encKey.E)
 }
 
-// NewEncryptionKey creates encryptionKey from string representation
+// NewEncryptionKey creates encryptionKey from string representation.
+// Both PEM blocks (PKIX or PKCS#1 public keys) and the legacy format produced by Encode are accepted.
 func NewEncryptionKey(text string) (EncryptionKey, error) {
+	if isPEM(text) {
+		return newPEMEncryptionKey(text)
+	}
 	msg := "wrong format of encryption key"
 	data := strings.Split(text, "#")
 	if len(data) != 2 {
@@ -75,8 +156,13 @@ func (dk *decryptionKey) Encode() string {
 	return result
 }
 
-// NewDecryptionKey creates a decryptionKey from its string representation
+// NewDecryptionKey creates a decryptionKey from its string representation.
+// Both unencrypted PEM blocks (PKCS#8 or PKCS#1 private keys) and the legacy format produced by Encode are accepted.
 func NewDecryptionKey(text string) (DecryptionKey, error) {
+	return NewDecryptionKeyWithPassphrase(text, nil)
+}
+
+func newLegacyDecryptionKey(text string) (DecryptionKey, error) {
 	errMsg := "wrong format of decryption key"
 	data := strings.Split(text, "#")
 	if len(data) != 4 {
@@ -0,0 +1,186 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// ChunkSize is the maximal length of plaintext encrypted in a single chunk of a stream.
+	ChunkSize = 1 << 16
+	// maxOverhead bounds the difference between the lengths of a chunk's ciphertext and plaintext.
+	maxOverhead    = 1 << 10
+	streamIDLength = 16
+)
+
+// streamWriter encrypts data written to it in authenticated chunks.
+type streamWriter struct {
+	key      SymmetricKey
+	w        io.Writer
+	streamID []byte
+	index    uint64
+	buf      []byte
+	closed   bool
+	err      error
+}
+
+// NewStreamWriter returns a writer encrypting the data in chunks with the given key and writing them to w.
+// Close has to be called to write the final chunk, otherwise the reader reports the stream as truncated;
+// it does not close w. The stream consists of a random stream id of 16 bytes, followed by chunks of the form
+// (1) whether it is the final chunk, 1 byte
+// (2) length of the ciphertext, 4 bytes as uint32
+// (3) ciphertext of at most ChunkSize bytes of data, with the stream id, the number of the chunk and (1) as associated data
+func NewStreamWriter(key SymmetricKey, w io.Writer) (io.WriteCloser, error) {
+	streamID := make([]byte, streamIDLength)
+	if _, err := io.ReadFull(rand.Reader, streamID); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(streamID); err != nil {
+		return nil, err
+	}
+	return &streamWriter{
+		key:      key,
+		w:        w,
+		streamID: streamID,
+	}, nil
+}
+
+func (sw *streamWriter) Write(data []byte) (int, error) {
+	if sw.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	sw.buf = append(sw.buf, data...)
+	for len(sw.buf) > ChunkSize {
+		if sw.err = sw.writeChunk(sw.buf[:ChunkSize], false); sw.err != nil {
+			return 0, sw.err
+		}
+		sw.buf = sw.buf[ChunkSize:]
+	}
+	return len(data), nil
+}
+
+// Close writes the final chunk.
+func (sw *streamWriter) Close() error {
+	if sw.closed {
+		return nil
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	sw.closed = true
+	sw.err = sw.writeChunk(sw.buf, true)
+	sw.buf = nil
+	return sw.err
+}
+
+func (sw *streamWriter) writeChunk(data []byte, final bool) error {
+	header := chunkHeader(final)
+	ct, err := sw.key.EncryptWithData(data, chunkData(sw.streamID, sw.index, header))
+	if err != nil {
+		return err
+	}
+	sw.index++
+	header = append(header, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(ct)))
+	if _, err := sw.w.Write(header); err != nil {
+		return err
+	}
+	_, err = sw.w.Write(ct)
+	return err
+}
+
+// streamReader decrypts a stream written by a streamWriter.
+type streamReader struct {
+	key      SymmetricKey
+	r        io.Reader
+	streamID []byte
+	index    uint64
+	plain    []byte
+	done     bool
+	err      error
+}
+
+// NewStreamReader returns a reader decrypting a stream created by a writer from NewStreamWriter with the same key.
+// Reading returns an error if the chunks were modified, reordered or truncated, and io.EOF after the final chunk.
+// Data in r following the final chunk is not read.
+func NewStreamReader(key SymmetricKey, r io.Reader) io.Reader {
+	return &streamReader{
+		key: key,
+		r:   r,
+	}
+}
+
+func (sr *streamReader) Read(buf []byte) (int, error) {
+	for len(sr.plain) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		sr.err = sr.readChunk()
+	}
+	n := copy(buf, sr.plain)
+	sr.plain = sr.plain[n:]
+	return n, nil
+}
+
+func (sr *streamReader) readChunk() error {
+	if sr.streamID == nil {
+		streamID := make([]byte, streamIDLength)
+		if _, err := io.ReadFull(sr.r, streamID); err != nil {
+			return truncated(err)
+		}
+		sr.streamID = streamID
+	}
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(sr.r, header); err != nil {
+		return truncated(err)
+	}
+	if header[0] > 1 {
+		return errors.New("malformed chunk header")
+	}
+	length := binary.LittleEndian.Uint32(header[1:])
+	if length > ChunkSize+maxOverhead {
+		return errors.New("chunk too long")
+	}
+	ct := make([]byte, length)
+	if _, err := io.ReadFull(sr.r, ct); err != nil {
+		return truncated(err)
+	}
+	plain, err := sr.key.DecryptWithData(ct, chunkData(sr.streamID, sr.index, header[:1]))
+	if err != nil {
+		return err
+	}
+	sr.index++
+	sr.plain = plain
+	sr.done = header[0] == 1
+	return nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("stream truncated")
+	}
+	return err
+}
+
+func chunkHeader(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// chunkData returns the associated data of a chunk.
+func chunkData(streamID []byte, index uint64, header []byte) []byte {
+	data := make([]byte, len(streamID)+8, len(streamID)+8+len(header))
+	copy(data, streamID)
+	binary.LittleEndian.PutUint64(data[len(streamID):], index)
+	return append(data, header...)
+}
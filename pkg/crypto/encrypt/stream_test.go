@@ -0,0 +1,93 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+var _ = Describe("Stream", func() {
+	var (
+		sk   SymmetricKey
+		data []byte
+	)
+	BeforeEach(func() {
+		var err error
+		sk, err = NewSymmetricKey([]byte("2137"))
+		Expect(err).NotTo(HaveOccurred())
+		data = make([]byte, 3*ChunkSize+17)
+		rand.Read(data)
+	})
+	encryptStream := func(data []byte) []byte {
+		buf := &bytes.Buffer{}
+		w, err := NewStreamWriter(sk, buf)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < len(data); i += 1000 {
+			end := i + 1000
+			if end > len(data) {
+				end = len(data)
+			}
+			_, err := w.Write(data[i:end])
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+		return buf.Bytes()
+	}
+	It("Should decrypt the stream", func() {
+		dec, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(encryptStream(data))))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(data))
+	})
+	It("Should decrypt an empty stream", func() {
+		dec, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(encryptStream(nil))))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(BeEmpty())
+	})
+	It("Should leave the data following the stream unread", func() {
+		stream := append(encryptStream(data[:100]), []byte("rest")...)
+		r := bytes.NewReader(stream)
+		dec, err := ioutil.ReadAll(NewStreamReader(sk, r))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(data[:100]))
+		rest, _ := ioutil.ReadAll(r)
+		Expect(rest).To(Equal([]byte("rest")))
+	})
+	It("Should detect truncation", func() {
+		stream := encryptStream(data)
+		_, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(stream[:len(stream)-50])))
+		Expect(err).To(HaveOccurred())
+		chunkLen := len(stream) / 4
+		_, err = ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(stream[:16+chunkLen])))
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(Equal(io.EOF))
+	})
+	It("Should detect reordered chunks", func() {
+		stream := encryptStream(data)
+		chunk := 5 + ChunkSize + 28
+		swapped := append([]byte{}, stream[:16]...)
+		swapped = append(swapped, stream[16+chunk:16+2*chunk]...)
+		swapped = append(swapped, stream[16:16+chunk]...)
+		swapped = append(swapped, stream[16+2*chunk:]...)
+		_, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(swapped)))
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should detect a chunk marked as final", func() {
+		stream := encryptStream(data)
+		stream[16] = 1
+		_, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(stream)))
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should detect chunks from a different stream", func() {
+		stream := encryptStream(data)
+		other := encryptStream(data)
+		copy(stream[:16], other[:16])
+		_, err := ioutil.ReadAll(NewStreamReader(sk, bytes.NewReader(stream)))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,140 @@
+package keystore
+
+import (
+	"errors"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+)
+
+var errPublicMismatch = errors.New("stored public key does not match the secret key")
+
+// SealBN256 encrypts a bn256 secret key using the passphrase.
+func SealBN256(sk *bn256.SecretKey, passphrase []byte) (*Key, error) {
+	return Seal(BN256, sk.VerificationKey().Encode(), sk.Marshal(), passphrase)
+}
+
+// BN256 decrypts the bn256 secret key stored in the Key.
+func (k *Key) BN256(passphrase []byte) (*bn256.SecretKey, error) {
+	if k.Type != BN256 {
+		return nil, errors.New("not a bn256 key")
+	}
+	data, err := k.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := new(bn256.SecretKey).Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if sk.VerificationKey().Encode() != k.Public {
+		return nil, errPublicMismatch
+	}
+	return sk, nil
+}
+
+// SealP2P encrypts a p2p secret key using the passphrase.
+func SealP2P(sk *p2p.SecretKey, passphrase []byte) (*Key, error) {
+	return Seal(P2P, sk.PublicKey().Encode(), sk.Marshal(), passphrase)
+}
+
+// P2P decrypts the p2p secret key stored in the Key.
+func (k *Key) P2P(passphrase []byte) (*p2p.SecretKey, error) {
+	if k.Type != P2P {
+		return nil, errors.New("not a p2p key")
+	}
+	data, err := k.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := new(p2p.SecretKey).Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if sk.PublicKey().Encode() != k.Public {
+		return nil, errPublicMismatch
+	}
+	return sk, nil
+}
+
+// SealRSA encrypts an RSA decryption key using the passphrase.
+func SealRSA(dk encrypt.DecryptionKey, passphrase []byte) (*Key, error) {
+	ek, err := encrypt.EncryptionKeyOf(dk)
+	if err != nil {
+		return nil, err
+	}
+	return Seal(RSA, ek.Encode(), []byte(dk.Encode()), passphrase)
+}
+
+// RSA decrypts the RSA decryption key stored in the Key.
+func (k *Key) RSA(passphrase []byte) (encrypt.DecryptionKey, error) {
+	if k.Type != RSA {
+		return nil, errors.New("not an rsa key")
+	}
+	data, err := k.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	dk, err := encrypt.NewDecryptionKey(string(data))
+	if err != nil {
+		return nil, err
+	}
+	ek, err := encrypt.EncryptionKeyOf(dk)
+	if err != nil {
+		return nil, err
+	}
+	if ek.Encode() != k.Public {
+		return nil, errPublicMismatch
+	}
+	return dk, nil
+}
+
+// SealThresholdKey encrypts a threshold key, including its secret share, using the passphrase.
+func SealThresholdKey(tk *tss.ThresholdKey, passphrase []byte) (*Key, error) {
+	return Seal(ThresholdKey, tk.GlobalVK().Encode(), tk.MarshalStorage(), passphrase)
+}
+
+// ThresholdKey decrypts the threshold key stored in the Key.
+func (k *Key) ThresholdKey(passphrase []byte) (*tss.ThresholdKey, error) {
+	if k.Type != ThresholdKey {
+		return nil, errors.New("not a threshold key")
+	}
+	data, err := k.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := tss.UnmarshalThresholdKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if tk.GlobalVK().Encode() != k.Public {
+		return nil, errPublicMismatch
+	}
+	return tk, nil
+}
+
+// SealWeakThresholdKey encrypts a weak threshold key, including its secret share, using the passphrase.
+func SealWeakThresholdKey(wtk *tss.WeakThresholdKey, passphrase []byte) (*Key, error) {
+	return Seal(WeakThresholdKey, wtk.GlobalVK().Encode(), wtk.MarshalStorage(), passphrase)
+}
+
+// WeakThresholdKey decrypts the weak threshold key stored in the Key.
+func (k *Key) WeakThresholdKey(passphrase []byte) (*tss.WeakThresholdKey, error) {
+	if k.Type != WeakThresholdKey {
+		return nil, errors.New("not a weak threshold key")
+	}
+	data, err := k.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	wtk, err := tss.UnmarshalWeakThresholdKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if wtk.GlobalVK().Encode() != k.Public {
+		return nil, errPublicMismatch
+	}
+	return wtk, nil
+}
@@ -0,0 +1,183 @@
+// Package keystore implements passphrase-protected storage of secret keys.
+//
+// A stored key is a JSON document containing the type of the key, its public part,
+// the creation time and the secret part encrypted with AES-GCM under a key derived
+// from the passphrase using scrypt. The metadata is bound to the encryption key,
+// so tampering with it makes decryption fail.
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+// KeyType describes what kind of secret key is stored.
+type KeyType string
+
+const (
+	// BN256 is a bn256.SecretKey used for signing.
+	BN256 KeyType = "bn256"
+	// P2P is a p2p.SecretKey used for deriving pairwise symmetric keys.
+	P2P KeyType = "p2p"
+	// RSA is an encrypt.DecryptionKey.
+	RSA KeyType = "rsa"
+	// ThresholdKey is a tss.ThresholdKey together with its secret share.
+	ThresholdKey KeyType = "tss"
+	// WeakThresholdKey is a tss.WeakThresholdKey together with its secret share.
+	WeakThresholdKey KeyType = "wtss"
+)
+
+const (
+	version   = 1
+	saltSize  = 32
+	derivSize = 32
+	// Parameters of scrypt, as recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	// Bounds on the parameters of scrypt read from files, so that a crafted file cannot exhaust memory or time.
+	maxScryptN  = 1 << 20
+	maxScryptRP = 1 << 10
+)
+
+// KDF describes the parameters of the key derivation function.
+type KDF struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+// Key is an encrypted secret key together with its metadata.
+type Key struct {
+	Version    int       `json:"version"`
+	Type       KeyType   `json:"type"`
+	Public     string    `json:"public"`
+	Created    time.Time `json:"created"`
+	KDF        KDF       `json:"kdf"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// Seal encrypts the secret using the passphrase and returns the resulting Key.
+// The public part should be the encoding of the public key matching the secret.
+func Seal(kt KeyType, public string, secret, passphrase []byte) (*Key, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	k := &Key{
+		Version: version,
+		Type:    kt,
+		Public:  public,
+		Created: time.Now().UTC().Truncate(time.Second),
+		KDF: KDF{
+			Name: "scrypt",
+			N:    scryptN,
+			R:    scryptR,
+			P:    scryptP,
+			Salt: salt,
+		},
+	}
+	sk, err := k.symmetricKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	k.Ciphertext, err = sk.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Open decrypts the secret stored in the Key using the passphrase.
+func (k *Key) Open(passphrase []byte) ([]byte, error) {
+	if k.Version != version {
+		return nil, errors.New("unsupported keystore version")
+	}
+	sk, err := k.symmetricKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := sk.Decrypt(k.Ciphertext)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted key")
+	}
+	return secret, nil
+}
+
+// Marshal the Key to its JSON representation.
+func (k *Key) Marshal() ([]byte, error) {
+	return json.MarshalIndent(k, "", "  ")
+}
+
+// Unmarshal a Key from its JSON representation.
+func Unmarshal(data []byte) (*Key, error) {
+	k := &Key{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Save writes the Key to the file with the given path, readable only by the owner.
+func Save(path string, k *Key) error {
+	data, err := k.Marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Load reads a Key from the file with the given path.
+func Load(path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+func (k *Key) symmetricKey(passphrase []byte) (encrypt.SymmetricKey, error) {
+	if k.KDF.Name != "scrypt" {
+		return nil, errors.New("unsupported key derivation function")
+	}
+	if k.KDF.N > maxScryptN || k.KDF.R <= 0 || k.KDF.P <= 0 || k.KDF.R > maxScryptRP/k.KDF.P {
+		return nil, errors.New("scrypt parameters out of bounds")
+	}
+	derived, err := scrypt.Key(passphrase, k.KDF.Salt, k.KDF.N, k.KDF.R, k.KDF.P, derivSize)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt.NewSymmetricKey(append(derived, k.digest()...))
+}
+
+// digest returns a hash of the metadata, so that it can be bound to the encryption key.
+func (k *Key) digest() []byte {
+	h := sha3.NewShake128()
+	buf := make([]byte, 8)
+	writeField := func(data []byte) {
+		binary.LittleEndian.PutUint64(buf, uint64(len(data)))
+		h.Write(buf)
+		h.Write(data)
+	}
+	binary.LittleEndian.PutUint64(buf, uint64(k.Version))
+	h.Write(buf)
+	writeField([]byte(k.Type))
+	writeField([]byte(k.Public))
+	binary.LittleEndian.PutUint64(buf, uint64(k.Created.Unix()))
+	h.Write(buf)
+	result := make([]byte, 32)
+	h.Read(result)
+	return result
+}
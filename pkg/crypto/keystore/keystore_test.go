@@ -0,0 +1,126 @@
+package keystore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/keystore"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+)
+
+var _ = Describe("Keystore", func() {
+	var passphrase []byte
+	BeforeEach(func() {
+		passphrase = []byte("correct horse battery staple")
+	})
+	Context("A bn256 key", func() {
+		var (
+			sk  *bn256.SecretKey
+			key *Key
+		)
+		BeforeEach(func() {
+			var err error
+			_, sk, err = bn256.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			key, err = SealBN256(sk, passphrase)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("Should carry the metadata", func() {
+			Expect(key.Type).To(Equal(BN256))
+			Expect(key.Public).To(Equal(sk.VerificationKey().Encode()))
+			Expect(key.Created.IsZero()).To(BeFalse())
+		})
+		It("Should be opened with the correct passphrase", func() {
+			sk2, err := key.BN256(passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sk2.Marshal()).To(Equal(sk.Marshal()))
+		})
+		It("Should not be opened with a wrong passphrase", func() {
+			_, err := key.BN256([]byte("wrong"))
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should not be opened as a key of a different type", func() {
+			_, err := key.P2P(passphrase)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should not be opened after the metadata was tampered with", func() {
+			pk, _, err := bn256.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			key.Public = pk.Encode()
+			_, err = key.BN256(passphrase)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should not be opened with too expensive scrypt parameters", func() {
+			key.KDF.N = 1 << 21
+			_, err := key.BN256(passphrase)
+			Expect(err).To(HaveOccurred())
+			key.KDF.N = 1 << 15
+			key.KDF.R, key.KDF.P = 1<<10, 2
+			_, err = key.BN256(passphrase)
+			Expect(err).To(HaveOccurred())
+			key.KDF.R, key.KDF.P = 8, 0
+			_, err = key.BN256(passphrase)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should survive saving to and loading from a file", func() {
+			dir, err := ioutil.TempDir("", "keystore")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "key.json")
+			Expect(Save(path, key)).To(Succeed())
+			loaded, err := Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			sk2, err := loaded.BN256(passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sk2.Marshal()).To(Equal(sk.Marshal()))
+		})
+	})
+	Context("A p2p key", func() {
+		It("Should be sealed and opened", func() {
+			_, sk, err := p2p.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			key, err := SealP2P(sk, passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := key.Marshal()
+			Expect(err).NotTo(HaveOccurred())
+			key, err = Unmarshal(data)
+			Expect(err).NotTo(HaveOccurred())
+			sk2, err := key.P2P(passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sk2.Marshal()).To(Equal(sk.Marshal()))
+		})
+	})
+	Context("An RSA key", func() {
+		It("Should be sealed and opened", func() {
+			_, dk, err := encrypt.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			key, err := SealRSA(dk, passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			dk2, err := key.RSA(passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dk2.Encode()).To(Equal(dk.Encode()))
+		})
+	})
+	Context("A weak threshold key", func() {
+		It("Should be sealed and opened", func() {
+			wtk := tss.SeededWTK(4, 2, 7, map[uint16]bool{1: true, 2: true, 3: true})
+			key, err := SealWeakThresholdKey(wtk, passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.Type).To(Equal(WeakThresholdKey))
+			_, err = key.ThresholdKey(passphrase)
+			Expect(err).To(HaveOccurred())
+			wtk2, err := key.WeakThresholdKey(passphrase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wtk2.ShareProviders()).To(Equal(wtk.ShareProviders()))
+			msg := []byte("msg")
+			Expect(wtk2.CreateShare(msg).Marshal()).To(Equal(wtk.CreateShare(msg).Marshal()))
+		})
+	})
+})
@@ -2,6 +2,8 @@ package multi
 
 import (
 	"crypto/subtle"
+	"errors"
+	"sort"
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
 )
@@ -17,19 +19,26 @@ type Keychain struct {
 }
 
 // NewKeychain creates a new keychain using the provided keys.
-func NewKeychain(pubs []*bn256.VerificationKey, priv *bn256.SecretKey) *Keychain {
+// It returns an error if the verification key corresponding to priv is not among pubs.
+func NewKeychain(pubs []*bn256.VerificationKey, priv *bn256.SecretKey) (*Keychain, error) {
 	ourPub := priv.VerificationKey().Marshal()
-	var pid uint16
 	for id, p := range pubs {
 		if subtle.ConstantTimeCompare(p.Marshal(), ourPub) == 1 {
-			pid = uint16(id)
-			break
+			return &Keychain{
+				pubs: pubs,
+				priv: priv,
+				pid:  uint16(id),
+			}, nil
 		}
 	}
+	return nil, errors.New("secret key does not match any of the public keys")
+}
+
+// NewVerifier creates a verify-only keychain, that can check signatures and multisignatures
+// made with the provided keys, but cannot sign.
+func NewVerifier(pubs []*bn256.VerificationKey) *Keychain {
 	return &Keychain{
 		pubs: pubs,
-		priv: priv,
-		pid:  pid,
 	}
 }
 
@@ -47,10 +56,19 @@ func (k *Keychain) Verify(pid uint16, data []byte) bool {
 }
 
 // Sign returns a signature for the provided data.
+// A verify-only keychain returns nil.
 func (k *Keychain) Sign(data []byte) []byte {
+	if k.priv == nil {
+		return nil
+	}
 	return k.priv.Sign(data).Marshal()
 }
 
+// CanSign checks whether the keychain contains a secret key.
+func (k *Keychain) CanSign() bool {
+	return k.priv != nil
+}
+
 // MultiVerify verifies whether the provided multisignature contains correctly signed data.
 func (k *Keychain) MultiVerify(s *Signature) bool {
 	if !s.complete() {
@@ -63,7 +81,87 @@ func (k *Keychain) MultiVerify(s *Signature) bool {
 	return multiKey.Verify(s.sgn, s.data)
 }
 
+// VerifyPartial verifies whether the provided multisignature, not necessarily complete,
+// contains correctly signed data by all the signers it includes.
+func (k *Keychain) VerifyPartial(s *Signature) bool {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.collected) == 0 {
+		return false
+	}
+	var multiKey *bn256.VerificationKey
+	for c := range s.collected {
+		if int(c) >= len(k.pubs) {
+			return false
+		}
+		multiKey = bn256.AddVerificationKeys(multiKey, k.pubs[c])
+	}
+	return multiKey.Verify(s.sgn, s.data)
+}
+
+// Diagnose finds the signers whose partial signatures included in the multisignature are incorrect.
+// It requires the individual signatures of all the signers to be known, i.e. to have been added with Aggregate.
+// The returned signers are sorted.
+func (k *Keychain) Diagnose(s *Signature) ([]uint16, error) {
+	s.Lock()
+	data, sgn := s.data, s.sgn
+	signers := make([]uint16, 0, len(s.collected))
+	parts := make(map[uint16]*bn256.Signature, len(s.collected))
+	for c := range s.collected {
+		signers = append(signers, c)
+		parts[c] = s.parts[c]
+	}
+	s.Unlock()
+	if len(signers) == 0 {
+		return nil, nil
+	}
+	sort.Slice(signers, func(i, j int) bool { return signers[i] < signers[j] })
+
+	var sum *bn256.Signature
+	bad := []uint16{}
+	valid := make([]uint16, 0, len(signers))
+	for _, c := range signers {
+		if parts[c] == nil {
+			return nil, errors.New("unknown signature of a signer")
+		}
+		sum = bn256.AddSignatures(sum, parts[c])
+		if int(c) >= len(k.pubs) {
+			bad = append(bad, c)
+		} else {
+			valid = append(valid, c)
+		}
+	}
+	if subtle.ConstantTimeCompare(sum.Marshal(), sgn.Marshal()) != 1 {
+		return nil, errors.New("multisignature differs from the sum of individual signatures")
+	}
+	bad = append(bad, k.bisect(valid, parts, data)...)
+	sort.Slice(bad, func(i, j int) bool { return bad[i] < bad[j] })
+	return bad, nil
+}
+
+// bisect returns the signers with incorrect signatures, checking halves of the set whenever their aggregate is incorrect.
+func (k *Keychain) bisect(signers []uint16, parts map[uint16]*bn256.Signature, data []byte) []uint16 {
+	if len(signers) == 0 {
+		return nil
+	}
+	var sgn *bn256.Signature
+	var key *bn256.VerificationKey
+	for _, c := range signers {
+		sgn = bn256.AddSignatures(sgn, parts[c])
+		key = bn256.AddVerificationKeys(key, k.pubs[c])
+	}
+	if key.Verify(sgn, data) {
+		return nil
+	}
+	if len(signers) == 1 {
+		return []uint16{signers[0]}
+	}
+	mid := len(signers) / 2
+	return append(k.bisect(signers[:mid], parts, data), k.bisect(signers[mid:], parts, data)...)
+}
+
 // Pid of the owner of the private key on this keychain.
+// It is meaningless for a verify-only keychain.
 func (k *Keychain) Pid() uint16 {
 	return k.pid
 }
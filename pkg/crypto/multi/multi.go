@@ -10,8 +10,10 @@
 package multi
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"sort"
 	"sync"
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
@@ -24,6 +26,7 @@ type Signature struct {
 	data      []byte
 	sgn       *bn256.Signature
 	collected map[uint16]bool
+	parts     map[uint16]*bn256.Signature
 }
 
 // NewSignature creates a signature for the given data with the given threshold.
@@ -33,12 +36,14 @@ func NewSignature(threshold uint16, data []byte) *Signature {
 		threshold: threshold,
 		data:      data,
 		collected: map[uint16]bool{},
+		parts:     map[uint16]*bn256.Signature{},
 	}
 }
 
 // Aggregate the given signature together with other signatures we received.
 // Returns true if the multisignature is complete.
-// The signature should be verified earlier.
+// The signature should be verified earlier, unless the complete multisignature is verified with MultiVerify.
+// The individual signature is retained, so that incorrect signatures can be found with Keychain.Diagnose and removed.
 func (s *Signature) Aggregate(pid uint16, sgnBytes []byte) (bool, error) {
 	sgn, err := new(bn256.Signature).Unmarshal(sgnBytes)
 	s.Lock()
@@ -54,36 +59,246 @@ func (s *Signature) Aggregate(pid uint16, sgnBytes []byte) (bool, error) {
 	}
 	s.sgn = bn256.AddSignatures(s.sgn, sgn)
 	s.collected[pid] = true
+	s.parts[pid] = sgn
 	return s.complete(), nil
 }
 
-// Marshal the multisignature to bytes.
-// Only marshals the multisignature itself and the list of partial signatures included.
-// Should only be called on complete proofs.
+// Merge the partial signatures aggregated in other, a multisignature of the same data, into this one.
+// Signers present in both multisignatures are counted once. This requires the individual signatures
+// of the common signers to be known to either of them, i.e. to have been added with Aggregate,
+// unless all the signers of one multisignature are present in the other.
+// Returns true if the multisignature is complete. The other multisignature should be verified earlier.
+func (s *Signature) Merge(other *Signature) (bool, error) {
+	if s == other {
+		s.Lock()
+		defer s.Unlock()
+		return s.complete(), nil
+	}
+	other.Lock()
+	data, sgn := other.data, other.sgn
+	collected := make(map[uint16]bool, len(other.collected))
+	for c := range other.collected {
+		collected[c] = true
+	}
+	parts := make(map[uint16]*bn256.Signature, len(other.parts))
+	for c, part := range other.parts {
+		parts[c] = part
+	}
+	other.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	if !bytes.Equal(s.data, data) {
+		return s.complete(), errors.New("multisignatures of different data")
+	}
+	common := []uint16{}
+	for c := range collected {
+		if s.collected[c] {
+			common = append(common, c)
+		}
+	}
+	switch {
+	case len(common) == len(collected):
+		// nothing new in other
+	case len(common) == len(s.collected):
+		s.sgn = sgn
+		s.collected = collected
+	default:
+		for _, c := range common {
+			part, ok := s.parts[c]
+			if !ok {
+				part, ok = parts[c]
+			}
+			if !ok {
+				return s.complete(), errors.New("unknown signature of a common signer")
+			}
+			sgn = bn256.AddSignatures(sgn, bn256.NegSignature(part))
+		}
+		s.sgn = bn256.AddSignatures(s.sgn, sgn)
+		for c := range collected {
+			s.collected[c] = true
+		}
+	}
+	for c, part := range parts {
+		if s.collected[c] && s.parts[c] == nil {
+			s.parts[c] = part
+		}
+	}
+	return s.complete(), nil
+}
+
+// Remove the partial signatures of the given signers from the multisignature.
+// Their individual signatures have to be known, i.e. have been added with Aggregate.
+func (s *Signature) Remove(pids []uint16) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, c := range pids {
+		if s.collected[c] && s.parts[c] == nil {
+			return errors.New("unknown signature of a signer")
+		}
+	}
+	for _, c := range pids {
+		if !s.collected[c] {
+			continue
+		}
+		s.sgn = bn256.AddSignatures(s.sgn, bn256.NegSignature(s.parts[c]))
+		delete(s.collected, c)
+		delete(s.parts, c)
+	}
+	if len(s.collected) == 0 {
+		s.sgn = nil
+	}
+	return nil
+}
+
+// Formats of encoded multisignatures.
+const (
+	// FormatRegular is the format of multisignatures encoded with Encode.
+	FormatRegular byte = 1
+	// FormatCompressed is the format of multisignatures encoded with EncodeCompressed.
+	FormatCompressed byte = 2
+)
+
+// Encode the multisignature in a canonical form for a committee of nProc members:
+// (1) format, 1 byte equal to FormatRegular
+// (2) nProc, 2 bytes as uint16
+// (3) bitmap of the members whose partial signatures are included, (nProc+7)/8 bytes, member i is the bit i%8 of the byte i/8
+// (4) marshalled multisignature
+// Any number of partial signatures can be encoded. Should only be called on complete proofs.
+func (s *Signature) Encode(nProc uint16) ([]byte, error) {
+	return s.encode(nProc, FormatRegular)
+}
+
+// EncodeCompressed encodes the multisignature like Encode, but with FormatCompressed as the format
+// and the multisignature marshalled in the compressed form.
+func (s *Signature) EncodeCompressed(nProc uint16) ([]byte, error) {
+	return s.encode(nProc, FormatCompressed)
+}
+
+func (s *Signature) encode(nProc uint16, format byte) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.sgn == nil {
+		return nil, errors.New("empty multisignature")
+	}
+	result := make([]byte, 3+bitmapLength(nProc), EncodedLength(format, nProc))
+	result[0] = format
+	binary.LittleEndian.PutUint16(result[1:], nProc)
+	for c := range s.collected {
+		if c >= nProc {
+			return nil, errors.New("signer out of range")
+		}
+		result[3+c/8] |= 1 << (c % 8)
+	}
+	if format == FormatCompressed {
+		return append(result, s.sgn.MarshalCompressed()...), nil
+	}
+	return append(result, s.sgn.Marshal()...), nil
+}
+
+// EncodedLength returns the length of a multisignature encoded in the given format for a committee of nProc members,
+// or 0 if the format is unknown.
+func EncodedLength(format byte, nProc uint16) int {
+	switch format {
+	case FormatRegular:
+		return 3 + bitmapLength(nProc) + SignatureLength
+	case FormatCompressed:
+		return 3 + bitmapLength(nProc) + bn256.CompressedSignatureLength
+	}
+	return 0
+}
+
+// Decode the multisignature encoded with Encode or EncodeCompressed for a committee of nProc members.
+// The receiver should contain the data and threshold that are the same as for the instance that was encoded.
+// An error is returned if the encoding is not canonical or contains less than threshold partial signatures.
+func (s *Signature) Decode(data []byte, nProc uint16) (*Signature, error) {
+	if len(data) == 0 {
+		return nil, errors.New("wrong data length")
+	}
+	format := data[0]
+	length := EncodedLength(format, nProc)
+	if length == 0 {
+		return nil, errors.New("unknown format of multisignature")
+	}
+	if len(data) != length {
+		return nil, errors.New("wrong data length")
+	}
+	if binary.LittleEndian.Uint16(data[1:]) != nProc {
+		return nil, errors.New("wrong committee size")
+	}
+	bitmap := data[3 : 3+bitmapLength(nProc)]
+	collected := map[uint16]bool{}
+	for i, b := range bitmap {
+		for j := uint16(0); j < 8; j++ {
+			if b&(1<<j) == 0 {
+				continue
+			}
+			c := uint16(i)*8 + j
+			if c >= nProc {
+				return nil, errors.New("signer out of range")
+			}
+			collected[c] = true
+		}
+	}
+	var sgn *bn256.Signature
+	var err error
+	if format == FormatCompressed {
+		sgn, err = new(bn256.Signature).UnmarshalCompressed(data[3+len(bitmap):])
+	} else {
+		sgn, err = new(bn256.Signature).Unmarshal(data[3+len(bitmap):])
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if len(collected) < int(s.threshold) {
+		return nil, errors.New("too few signers")
+	}
+	s.collected = collected
+	s.parts = map[uint16]*bn256.Signature{}
+	s.sgn = sgn
+	return s, nil
+}
+
+func bitmapLength(nProc uint16) int {
+	return (int(nProc) + 7) / 8
+}
+
+// Marshal the multisignature to bytes in the legacy form.
+// Only marshals the multisignature itself and the list of partial signatures included, in increasing order.
+// Should only be called on complete proofs. New code should use Encode.
 func (s *Signature) Marshal() []byte {
 	s.Lock()
 	defer s.Unlock()
-	result := make([]byte, len(s.collected)*2)
-	i := 0
+	signers := make([]uint16, 0, len(s.collected))
 	for c := range s.collected {
-		binary.LittleEndian.PutUint16(result[i:i+2], uint16(c))
-		i += 2
+		signers = append(signers, c)
+	}
+	sort.Slice(signers, func(i, j int) bool { return signers[i] < signers[j] })
+	result := make([]byte, len(signers)*2)
+	for i, c := range signers {
+		binary.LittleEndian.PutUint16(result[2*i:2*i+2], c)
 	}
 	return append(result, s.sgn.Marshal()...)
 }
 
-// MarshaledLength returns how long would a marshaling of this proof be, in bytes.
+// MarshaledLength returns how long would a legacy marshaling of a proof with exactly threshold signers be, in bytes.
 func (s *Signature) MarshaledLength() int {
 	return int(s.threshold)*2 + SignatureLength
 }
 
-// Unmarshal the multisignature from bytes.
+// Unmarshal the multisignature from bytes in the legacy form, containing exactly threshold partial signatures.
 // The receiver should contain the data and threshold that are the same as for the instance that was marshaled.
 // If the unmarshaled signature is incorrect an error is returned.
 func (s *Signature) Unmarshal(data []byte) (*Signature, error) {
 	s.Lock()
 	defer s.Unlock()
+	if len(data) < 2*int(s.threshold) {
+		return s, errors.New("data too short")
+	}
 	s.collected = map[uint16]bool{}
+	s.parts = map[uint16]*bn256.Signature{}
 	for i := 0; i < 2*int(s.threshold); i += 2 {
 		c := binary.LittleEndian.Uint16(data[i : i+2])
 		s.collected[c] = true
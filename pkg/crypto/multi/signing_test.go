@@ -12,22 +12,43 @@ import (
 var _ = Describe("Signing", func() {
 	var (
 		keys []*Keychain
+		pubs []*bn256.VerificationKey
 		n    uint16
 	)
 	BeforeEach(func() {
 		n = 10
 		keys = make([]*Keychain, n)
 		privs := make([]*bn256.SecretKey, n)
-		pubs := make([]*bn256.VerificationKey, n)
+		pubs = make([]*bn256.VerificationKey, n)
 		for i := range keys {
 			var err error
 			pubs[i], privs[i], err = bn256.GenerateKeys()
 			Expect(err).NotTo(HaveOccurred())
 		}
 		for i := range keys {
-			keys[i] = NewKeychain(pubs, privs[i])
+			var err error
+			keys[i], err = NewKeychain(pubs, privs[i])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys[i].Pid()).To(Equal(uint16(i)))
 		}
 	})
+	It("should not create a keychain with a foreign secret key", func() {
+		_, priv, err := bn256.GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = NewKeychain(pubs, priv)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should verify but not sign with a verify-only keychain", func() {
+		verifier := NewVerifier(pubs)
+		Expect(verifier.CanSign()).To(BeFalse())
+		Expect(verifier.Sign([]byte("data"))).To(BeNil())
+		data := []byte("data")
+		Expect(verifier.Verify(3, append(data, keys[3].Sign(data)...))).To(BeTrue())
+		multisig := NewSignature(2, data)
+		multisig.Aggregate(0, keys[0].Sign(data))
+		multisig.Aggregate(1, keys[1].Sign(data))
+		Expect(verifier.MultiVerify(multisig)).To(BeTrue())
+	})
 	Describe("Data", func() {
 		var (
 			data []byte
@@ -113,6 +134,182 @@ var _ = Describe("Signing", func() {
 				Expect(done).To(BeFalse())
 				Expect(keys[0].MultiVerify(multisig)).To(BeFalse())
 			})
+			It("should verify after threshold signatures aggregated with encoding/decoding", func() {
+				for i := uint16(0); i < threshold; i++ {
+					multisig.Aggregate(i, keys[i].Sign(data))
+				}
+				encoded, err := multisig.Encode(n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(encoded).To(HaveLen(EncodedLength(FormatRegular, n)))
+				decoded, err := NewSignature(threshold, data).Decode(encoded, n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(keys[0].MultiVerify(decoded)).To(BeTrue())
+				reencoded, err := decoded.Encode(n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reencoded).To(Equal(encoded))
+			})
+			It("should encode and decode more than threshold signatures", func() {
+				full := NewSignature(n, data)
+				for i := n; i > 0; i-- {
+					full.Aggregate(i-1, keys[i-1].Sign(data))
+				}
+				encoded, err := full.Encode(n)
+				Expect(err).NotTo(HaveOccurred())
+				decoded, err := NewSignature(threshold, data).Decode(encoded, n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(keys[0].MultiVerify(decoded)).To(BeTrue())
+			})
+			It("should not decode malformed encodings", func() {
+				for i := uint16(0); i < threshold; i++ {
+					multisig.Aggregate(i, keys[i].Sign(data))
+				}
+				encoded, _ := multisig.Encode(n)
+				_, err := NewSignature(threshold, data).Decode(encoded, n+1)
+				Expect(err).To(HaveOccurred())
+				_, err = NewSignature(threshold, data).Decode(encoded[:len(encoded)-1], n)
+				Expect(err).To(HaveOccurred())
+				_, err = NewSignature(threshold+1, data).Decode(encoded, n)
+				Expect(err).To(HaveOccurred())
+				outOfRange := append([]byte{}, encoded...)
+				outOfRange[4] |= 0x80
+				_, err = NewSignature(threshold, data).Decode(outOfRange, n)
+				Expect(err).To(HaveOccurred())
+				unknown := append([]byte{}, encoded...)
+				unknown[0] = 0
+				_, err = NewSignature(threshold, data).Decode(unknown, n)
+				Expect(err).To(HaveOccurred())
+			})
+			It("should verify after encoding and decoding in the compressed format", func() {
+				for i := uint16(0); i < threshold; i++ {
+					multisig.Aggregate(i, keys[i].Sign(data))
+				}
+				encoded, err := multisig.EncodeCompressed(n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(encoded).To(HaveLen(EncodedLength(FormatCompressed, n)))
+				Expect(len(encoded)).To(BeNumerically("<", EncodedLength(FormatRegular, n)))
+				decoded, err := NewSignature(threshold, data).Decode(encoded, n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(keys[0].MultiVerify(decoded)).To(BeTrue())
+				reencoded, err := decoded.EncodeCompressed(n)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reencoded).To(Equal(encoded))
+			})
+			It("should marshal signers in increasing order", func() {
+				for i := threshold; i > 0; i-- {
+					multisig.Aggregate(i-1, keys[i-1].Sign(data))
+				}
+				marshaled := multisig.Marshal()
+				Expect(marshaled[:4]).To(Equal([]byte{0, 0, 1, 0}))
+				Expect(marshaled).To(HaveLen(multisig.MarshaledLength()))
+			})
+			Context("When merging", func() {
+				var other *Signature
+				BeforeEach(func() {
+					other = NewSignature(threshold, data)
+				})
+				It("should merge disjoint partial multisignatures", func() {
+					for i := uint16(0); i < threshold/2; i++ {
+						multisig.Aggregate(i, keys[i].Sign(data))
+					}
+					for i := threshold / 2; i < threshold; i++ {
+						other.Aggregate(i, keys[i].Sign(data))
+					}
+					Expect(keys[0].VerifyPartial(multisig)).To(BeTrue())
+					Expect(keys[0].VerifyPartial(other)).To(BeTrue())
+					done, err := multisig.Merge(other)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(done).To(BeTrue())
+					Expect(keys[0].MultiVerify(multisig)).To(BeTrue())
+				})
+				It("should merge overlapping partial multisignatures with known signatures", func() {
+					for i := uint16(0); i < threshold-1; i++ {
+						multisig.Aggregate(i, keys[i].Sign(data))
+					}
+					for i := uint16(1); i < threshold+1; i++ {
+						other.Aggregate(i, keys[i].Sign(data))
+					}
+					decoded := NewSignature(1, data)
+					_, err := decoded.Decode(mustEncode(other, n), n)
+					Expect(err).NotTo(HaveOccurred())
+					done, err := multisig.Merge(decoded)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(done).To(BeTrue())
+					Expect(keys[0].MultiVerify(multisig)).To(BeTrue())
+				})
+				It("should merge a multisignature containing all its signers", func() {
+					for i := uint16(0); i < threshold; i++ {
+						other.Aggregate(i, keys[i].Sign(data))
+					}
+					multisig.Aggregate(1, keys[1].Sign(data))
+					decoded := NewSignature(1, data)
+					_, err := decoded.Decode(mustEncode(other, n), n)
+					Expect(err).NotTo(HaveOccurred())
+					done, err := multisig.Merge(decoded)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(done).To(BeTrue())
+					Expect(keys[0].MultiVerify(multisig)).To(BeTrue())
+				})
+				It("should fail on overlapping multisignatures with unknown signatures", func() {
+					for i := uint16(0); i < 2; i++ {
+						multisig.Aggregate(i, keys[i].Sign(data))
+						other.Aggregate(i+1, keys[i+1].Sign(data))
+					}
+					first := NewSignature(1, data)
+					_, err := first.Decode(mustEncode(multisig, n), n)
+					Expect(err).NotTo(HaveOccurred())
+					second := NewSignature(1, data)
+					_, err = second.Decode(mustEncode(other, n), n)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = first.Merge(second)
+					Expect(err).To(HaveOccurred())
+					Expect(keys[0].VerifyPartial(first)).To(BeTrue())
+				})
+				It("should fail on multisignatures of different data", func() {
+					other = NewSignature(threshold, append(data, 1))
+					other.Aggregate(0, keys[0].Sign(append(data, 1)))
+					_, err := multisig.Merge(other)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			It("should diagnose and remove incorrect signatures", func() {
+				multisig = NewSignature(n, data)
+				for i := uint16(0); i < n; i++ {
+					sgn := keys[i].Sign(data)
+					if i == 3 || i == 7 {
+						sgn = keys[i].Sign(append(data, 1))
+					}
+					multisig.Aggregate(i, sgn)
+				}
+				Expect(keys[0].MultiVerify(multisig)).To(BeFalse())
+				bad, err := keys[0].Diagnose(multisig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bad).To(Equal([]uint16{3, 7}))
+				Expect(multisig.Remove(bad)).To(Succeed())
+				Expect(keys[0].VerifyPartial(multisig)).To(BeTrue())
+			})
+			It("should diagnose no incorrect signatures in a correct multisignature", func() {
+				for i := uint16(0); i < threshold; i++ {
+					multisig.Aggregate(i, keys[i].Sign(data))
+				}
+				bad, err := keys[0].Diagnose(multisig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bad).To(BeEmpty())
+			})
+			It("should not diagnose without individual signatures", func() {
+				for i := uint16(0); i < threshold; i++ {
+					multisig.Aggregate(i, keys[i].Sign(data))
+				}
+				decoded, err := NewSignature(threshold, data).Decode(mustEncode(multisig, n), n)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = keys[0].Diagnose(decoded)
+				Expect(err).To(HaveOccurred())
+				Expect(decoded.Remove([]uint16{0})).NotTo(Succeed())
+			})
+			It("should not verify partially an incorrect aggregate", func() {
+				Expect(keys[0].VerifyPartial(multisig)).To(BeFalse())
+				multisig.Aggregate(1, keys[0].Sign(data))
+				Expect(keys[0].VerifyPartial(multisig)).To(BeFalse())
+			})
 			It("should not verify when signatures aggregated with incorrect pids", func() {
 				for i := uint16(0); i < threshold-1; i++ {
 					done, err := multisig.Aggregate(i+1, keys[i].Sign(data))
@@ -128,3 +325,9 @@ var _ = Describe("Signing", func() {
 	})
 
 })
+
+func mustEncode(s *Signature, nProc uint16) []byte {
+	data, err := s.Encode(nProc)
+	Expect(err).NotTo(HaveOccurred())
+	return data
+}
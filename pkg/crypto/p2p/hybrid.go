@@ -0,0 +1,165 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+
+	"github.com/cloudflare/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+// g2Length is the length of a marshalled element of bn256.G2.
+const g2Length = 128
+
+// hybridLabel separates points and keys of the hybrid encryption from any other use of the p2p keys.
+var hybridLabel = []byte("p2p-hybrid")
+
+// The p2p keys can be used as hybrid encryption keys for messages of arbitrary size.
+var (
+	_ encrypt.EncryptionKey = (*PublicKey)(nil)
+	_ encrypt.DecryptionKey = (*SecretKey)(nil)
+)
+
+// Encrypt encrypts msg so that only the owner of the corresponding secret key can decrypt it.
+// It does not require any shared secret with the recipient.
+// The result is of the form
+// (1) marshalled ephemeral public key, an element of bn256.G2
+// (2) msg encrypted with a symmetric key derived from the ephemeral key and the secret of the recipient.
+func (pk *PublicKey) Encrypt(msg []byte) (encrypt.CipherText, error) {
+	return pk.EncryptWithData(msg, nil)
+}
+
+// EncryptWithData works like Encrypt, but additionally binds the ciphertext to the associated data ad,
+// which is authenticated but not encrypted.
+func (pk *PublicKey) EncryptWithData(msg, ad []byte) (encrypt.CipherText, error) {
+	r, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral := new(bn256.G2).ScalarBaseMult(r)
+	shared := new(bn256.GT).ScalarMult(bn256.Pair(hashEphemeral(ephemeral, ad), &pk.g2), r)
+	key, err := hybridKey(ephemeral, shared)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := key.EncryptWithData(msg, ad)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeral.Marshal(), ct...), nil
+}
+
+// Decrypt decrypts a ciphertext created by Encrypt of the corresponding public key.
+func (sk *SecretKey) Decrypt(ct encrypt.CipherText) ([]byte, error) {
+	return sk.DecryptWithData(ct, nil)
+}
+
+// DecryptWithData decrypts a ciphertext created by EncryptWithData of the corresponding public key with the same associated data.
+func (sk *SecretKey) DecryptWithData(ct encrypt.CipherText, ad []byte) ([]byte, error) {
+	ds, err := NewDecryptionSecret(sk, ct, ad)
+	if err != nil {
+		return nil, err
+	}
+	return ds.DecryptWithData(ct, ad)
+}
+
+// DecryptionSecret is the secret from which the key of a single hybrid ciphertext is derived.
+// It should be revealed, when proving that the ciphertext was not compliant,
+// since it allows decrypting only that ciphertext without disclosing the secret key.
+// It is the secret key applied to a hash of the ephemeral key and the associated data, so even for
+// an ephemeral key chosen by a malicious sender it reveals nothing about secrets shared with other parties,
+// nor about other ciphertexts.
+type DecryptionSecret struct {
+	secret *bn256.G1
+}
+
+// NewDecryptionSecret returns the secret needed to decrypt ct with the associated data ad,
+// created by EncryptWithData of the public key corresponding to sk.
+func NewDecryptionSecret(sk *SecretKey, ct encrypt.CipherText, ad []byte) (DecryptionSecret, error) {
+	ephemeral, err := ephemeralKey(ct)
+	if err != nil {
+		return DecryptionSecret{}, err
+	}
+	return DecryptionSecret{new(bn256.G1).ScalarMult(hashEphemeral(ephemeral, ad), &sk.key)}, nil
+}
+
+// VerifyDecryptionSecret checks whether ds is the secret of ct with the associated data ad created by EncryptWithData of pk.
+func VerifyDecryptionSecret(pk *PublicKey, ct encrypt.CipherText, ad []byte, ds DecryptionSecret) bool {
+	ephemeral, err := ephemeralKey(ct)
+	if err != nil || ds.secret == nil {
+		return false
+	}
+	p1 := bn256.Pair(ds.secret, genG2).Marshal()
+	p2 := bn256.Pair(hashEphemeral(ephemeral, ad), &pk.g2).Marshal()
+	return subtle.ConstantTimeCompare(p1, p2) == 1
+}
+
+// Decrypt decrypts the ciphertext the secret was created for.
+func (ds DecryptionSecret) Decrypt(ct encrypt.CipherText) ([]byte, error) {
+	return ds.DecryptWithData(ct, nil)
+}
+
+// DecryptWithData decrypts the ciphertext the secret was created for, checking that it is bound to ad.
+func (ds DecryptionSecret) DecryptWithData(ct encrypt.CipherText, ad []byte) ([]byte, error) {
+	ephemeral, err := ephemeralKey(ct)
+	if err != nil {
+		return nil, err
+	}
+	if ds.secret == nil {
+		return nil, errors.New("empty decryption secret")
+	}
+	key, err := hybridKey(ephemeral, bn256.Pair(ds.secret, ephemeral))
+	if err != nil {
+		return nil, err
+	}
+	return key.DecryptWithData(ct[g2Length:], ad)
+}
+
+// Marshal the decryption secret to bytes. An empty secret is marshalled as the neutral element.
+func (ds *DecryptionSecret) Marshal() []byte {
+	if ds.secret == nil {
+		return new(bn256.G1).ScalarBaseMult(new(big.Int)).Marshal()
+	}
+	return ds.secret.Marshal()
+}
+
+// Unmarshal the decryption secret from bytes.
+func (ds *DecryptionSecret) Unmarshal(data []byte) (*DecryptionSecret, error) {
+	secret := new(bn256.G1)
+	if _, err := secret.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	ds.secret = secret
+	return ds, nil
+}
+
+// WellFormed checks whether ct starts with an ephemeral public key, as ciphertexts created by Encrypt do.
+func WellFormed(ct encrypt.CipherText) bool {
+	_, err := ephemeralKey(ct)
+	return err == nil
+}
+
+// ephemeralKey reads the ephemeral public key of a ciphertext created by Encrypt.
+func ephemeralKey(ct encrypt.CipherText) (*bn256.G2, error) {
+	if len(ct) < g2Length {
+		return nil, errors.New("ciphertext too short")
+	}
+	ephemeral := new(bn256.G2)
+	if _, err := ephemeral.Unmarshal(ct[:g2Length]); err != nil {
+		return nil, err
+	}
+	return ephemeral, nil
+}
+
+// hashEphemeral maps the ephemeral key and the associated data of a ciphertext to the point
+// the secret key of the recipient is applied to.
+func hashEphemeral(ephemeral *bn256.G2, ad []byte) *bn256.G1 {
+	return bn256.HashG1(append(ephemeral.Marshal(), ad...), hybridLabel)
+}
+
+func hybridKey(ephemeral *bn256.G2, shared *bn256.GT) (encrypt.SymmetricKey, error) {
+	data := append(append([]byte{}, hybridLabel...), ephemeral.Marshal()...)
+	return encrypt.NewSymmetricKey(append(data, shared.Marshal()...))
+}
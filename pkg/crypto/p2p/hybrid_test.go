@@ -0,0 +1,106 @@
+package p2p_test
+
+import (
+	. "gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hybrid encryption", func() {
+	var (
+		sk1, sk2 *SecretKey
+		pk1      *PublicKey
+		msg      []byte
+	)
+	BeforeEach(func() {
+		var err error
+		pk1, sk1, err = GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		_, sk2, err = GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		msg = []byte("hybrid")
+	})
+	It("Should be decrypted by the owner of the secret key", func() {
+		ct, err := pk1.Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := sk1.Decrypt(ct)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+	})
+	It("Should not be decrypted with a different secret key", func() {
+		ct, _ := pk1.Encrypt(msg)
+		_, err := sk2.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should be randomized", func() {
+		ct1, _ := pk1.Encrypt(msg)
+		ct2, _ := pk1.Encrypt(msg)
+		Expect(ct1).NotTo(Equal(ct2))
+	})
+	It("Should reject tampered and truncated ciphertexts", func() {
+		ct, _ := pk1.Encrypt(msg)
+		ct[len(ct)-1] ^= 1
+		_, err := sk1.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+		_, err = sk1.Decrypt(ct[:10])
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should be decrypted only with the same associated data", func() {
+		ct, err := pk1.EncryptWithData(msg, []byte("ad"))
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := sk1.DecryptWithData(ct, []byte("ad"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+		_, err = sk1.DecryptWithData(ct, []byte("other"))
+		Expect(err).To(HaveOccurred())
+		_, err = sk1.Decrypt(ct)
+		Expect(err).To(HaveOccurred())
+	})
+	Context("Decryption secret", func() {
+		It("Should decrypt only the ciphertext it was created for", func() {
+			ct, _ := pk1.Encrypt(msg)
+			other, _ := pk1.Encrypt(msg)
+			ds, err := NewDecryptionSecret(sk1, ct, nil)
+			Expect(err).NotTo(HaveOccurred())
+			dec, err := ds.Decrypt(ct)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dec).To(Equal(msg))
+			_, err = ds.Decrypt(other)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should be verified against the public key and the ciphertext", func() {
+			ct, _ := pk1.Encrypt(msg)
+			other, _ := pk1.Encrypt(msg)
+			ds, _ := NewDecryptionSecret(sk1, ct, nil)
+			Expect(VerifyDecryptionSecret(pk1, ct, nil, ds)).To(BeTrue())
+			Expect(VerifyDecryptionSecret(pk1, other, nil, ds)).To(BeFalse())
+			wrong, _ := NewDecryptionSecret(sk2, ct, nil)
+			Expect(VerifyDecryptionSecret(pk1, ct, nil, wrong)).To(BeFalse())
+		})
+		It("Should be bound to the associated data", func() {
+			ct, _ := pk1.EncryptWithData(msg, []byte("ad"))
+			ds, _ := NewDecryptionSecret(sk1, ct, []byte("ad"))
+			Expect(VerifyDecryptionSecret(pk1, ct, []byte("ad"), ds)).To(BeTrue())
+			Expect(VerifyDecryptionSecret(pk1, ct, []byte("other"), ds)).To(BeFalse())
+			_, err := ds.DecryptWithData(ct, []byte("other"))
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should not reveal the secret shared with the owner of a key used as the ephemeral key", func() {
+			pk2 := sk2.PublicKey()
+			forged := append(pk2.Marshal()[4+64:], make([]byte, 32)...)
+			ds, err := NewDecryptionSecret(sk1, forged, nil)
+			Expect(err).NotTo(HaveOccurred())
+			shared := NewSharedSecret(sk1, pk2)
+			Expect(ds.Marshal()).NotTo(Equal(shared.Marshal()))
+		})
+		It("Should survive marshalling and unmarshalling", func() {
+			ct, _ := pk1.Encrypt(msg)
+			ds, _ := NewDecryptionSecret(sk1, ct, nil)
+			var decoded DecryptionSecret
+			_, err := decoded.Unmarshal(ds.Marshal())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(VerifyDecryptionSecret(pk1, ct, nil, decoded)).To(BeTrue())
+		})
+	})
+})
@@ -12,3 +12,43 @@ func MinimalQuorum(nProcesses uint16) uint16 {
 func MinimalTrusted(nProcesses uint16) uint16 {
 	return (nProcesses-1)/3 + 1
 }
+
+// TotalWeight returns the sum of the weights of all processes.
+// When processes are weighted, less than 1/3 of the total weight can be byzantine.
+// The sum is accumulated in uint64, so it never wraps around.
+func TotalWeight(weights []uint16) uint64 {
+	total := uint64(0)
+	for _, w := range weights {
+		total += uint64(w)
+	}
+	return total
+}
+
+// MinimalWeightedQuorum is the minimal total weight of a subset forming a quorum within processes with the given weights.
+func MinimalWeightedQuorum(weights []uint16) uint64 {
+	total := TotalWeight(weights)
+	return total - total/3
+}
+
+// MinimalWeightedTrusted is the minimal total weight of a subset of processes with the given weights,
+// that guarantees that the subset contains at least one honest process.
+func MinimalWeightedTrusted(weights []uint16) uint64 {
+	total := TotalWeight(weights)
+	if total == 0 {
+		return 0
+	}
+	return (total-1)/3 + 1
+}
+
+// WeightOf returns the total weight of the given processes, counting every process once.
+func WeightOf(weights []uint16, pids []uint16) uint64 {
+	seen := map[uint16]bool{}
+	total := uint64(0)
+	for _, pid := range pids {
+		if int(pid) < len(weights) && !seen[pid] {
+			seen[pid] = true
+			total += uint64(weights[pid])
+		}
+	}
+	return total
+}
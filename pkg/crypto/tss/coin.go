@@ -0,0 +1,116 @@
+package tss
+
+import (
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Coin is a common random coin based on a threshold key.
+// For every nonce threshold many shares combine into the same unpredictable signature,
+// which is then turned into random values identical on all the nodes.
+type Coin struct {
+	tk          *ThresholdKey
+	createShare func([]byte) *Share
+}
+
+// Toss is the outcome of the coin for a single nonce.
+type Toss struct {
+	sgn *Signature
+}
+
+// NewCoin returns a coin based on the given ThresholdKey.
+func NewCoin(tk *ThresholdKey) *Coin {
+	return &Coin{tk: tk, createShare: tk.CreateShare}
+}
+
+// NewWeakCoin returns a coin based on the given WeakThresholdKey.
+// Only share providers can create shares of such a coin.
+func NewWeakCoin(wtk *WeakThresholdKey) *Coin {
+	return &Coin{tk: &wtk.ThresholdKey, createShare: wtk.CreateShare}
+}
+
+// coinMessage returns the message signed to toss the coin for the given nonce.
+// It is domain separated from messages signed with the threshold key for other purposes.
+func coinMessage(nonce uint64) []byte {
+	msg := []byte("tss-coin")
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, nonce)
+	return append(msg, buf...)
+}
+
+// CreateShare creates a share of the coin for the given nonce.
+// It returns nil if the owner of the key cannot produce shares.
+func (c *Coin) CreateShare(nonce uint64) *Share {
+	return c.createShare(coinMessage(nonce))
+}
+
+// VerifyShare verifies whether the given share of the coin for the nonce is correct.
+func (c *Coin) VerifyShare(sh *Share, nonce uint64) bool {
+	if int(sh.owner) >= len(c.tk.vks) {
+		return false
+	}
+	return c.tk.VerifyShare(sh, coinMessage(nonce))
+}
+
+// Combine combines the given shares of the coin for the nonce, skipping the invalid ones.
+// It returns the Toss and a bool value indicating whether enough valid shares were given.
+func (c *Coin) Combine(shares []*Share, nonce uint64) (*Toss, bool) {
+	sgn, _, ok := c.tk.CombineSharesRobust(shares, coinMessage(nonce))
+	if !ok {
+		return nil, false
+	}
+	return &Toss{sgn}, true
+}
+
+// Toss returns the Toss for the nonce given the combined signature, for example received from another node.
+// It returns false if the signature is invalid.
+func (c *Coin) Toss(sgn *Signature, nonce uint64) (*Toss, bool) {
+	if !c.tk.VerifySignature(sgn, coinMessage(nonce)) {
+		return nil, false
+	}
+	return &Toss{sgn}, true
+}
+
+// Signature returns the combined signature determining the toss.
+func (t *Toss) Signature() *Signature {
+	return t.sgn
+}
+
+// stream returns an infinite stream of random bytes determined by the toss.
+func (t *Toss) stream() io.Reader {
+	h := sha3.NewShake256()
+	h.Write([]byte("tss-coin-toss"))
+	h.Write(t.sgn.Marshal())
+	return h
+}
+
+// Bit returns a random bit, 0 or 1.
+func (t *Toss) Bit() byte {
+	return t.Bytes(1)[0] & 1
+}
+
+// Bytes returns n random bytes.
+func (t *Toss) Bytes(n int) []byte {
+	result := make([]byte, n)
+	t.stream().Read(result)
+	return result
+}
+
+// Int returns a random integer uniformly distributed in [0, bound).
+// It uses rejection sampling, so there is no bias for any bound. Bound has to be positive.
+func (t *Toss) Int(bound uint64) uint64 {
+	// rem is 2^64 mod bound. The values in [2^64 - rem, 2^64) are rejected,
+	// so that every remainder modulo bound is equally likely.
+	rem := -bound % bound
+	stream := t.stream()
+	buf := make([]byte, 8)
+	for {
+		stream.Read(buf)
+		v := binary.LittleEndian.Uint64(buf)
+		if rem == 0 || v < -rem {
+			return v % bound
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tss_test
+
+import (
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coin", func() {
+	var (
+		n, t   uint16
+		coins  []*Coin
+		shares []*Share
+		nonce  uint64
+	)
+	BeforeEach(func() {
+		n, nonce = 4, 17
+		shareProviders := map[uint16]bool{0: true, 1: true, 3: true}
+		coins = make([]*Coin, n)
+		for i := uint16(0); i < n; i++ {
+			wtk := SeededWTK(n, i, 2137, shareProviders)
+			t = wtk.Threshold()
+			coins[i] = NewWeakCoin(wtk)
+		}
+		shares = nil
+		for i := uint16(0); i < n; i++ {
+			if sh := coins[i].CreateShare(nonce); sh != nil {
+				shares = append(shares, sh)
+			}
+		}
+	})
+	It("should produce shares only for share providers", func() {
+		Expect(coins[2].CreateShare(nonce)).To(BeNil())
+		Expect(shares).To(HaveLen(3))
+	})
+	It("should verify shares", func() {
+		for _, sh := range shares {
+			Expect(coins[2].VerifyShare(sh, nonce)).To(BeTrue())
+			Expect(coins[2].VerifyShare(sh, nonce+1)).To(BeFalse())
+		}
+	})
+	It("should give identical results on all nodes for any set of shares", func() {
+		toss0, ok := coins[0].Combine(shares[:t], nonce)
+		Expect(ok).To(BeTrue())
+		toss2, ok := coins[2].Combine(shares[len(shares)-int(t):], nonce)
+		Expect(ok).To(BeTrue())
+		Expect(toss0.Bit()).To(Equal(toss2.Bit()))
+		Expect(toss0.Bytes(40)).To(Equal(toss2.Bytes(40)))
+		Expect(toss0.Int(1000)).To(Equal(toss2.Int(1000)))
+	})
+	It("should skip invalid shares when combining", func() {
+		bad := coins[1].CreateShare(nonce + 1)
+		toss, ok := coins[0].Combine(append([]*Share{bad}, shares...), nonce)
+		Expect(ok).To(BeTrue())
+		Expect(coins[0].VerifyShare(bad, nonce)).To(BeFalse())
+		expected, _ := coins[0].Combine(shares, nonce)
+		Expect(toss.Bytes(8)).To(Equal(expected.Bytes(8)))
+	})
+	It("should not combine too few shares", func() {
+		_, ok := coins[0].Combine(shares[:t-1], nonce)
+		Expect(ok).To(BeFalse())
+	})
+	It("should accept only valid combined signatures", func() {
+		toss, ok := coins[0].Combine(shares, nonce)
+		Expect(ok).To(BeTrue())
+		received, ok := coins[3].Toss(toss.Signature(), nonce)
+		Expect(ok).To(BeTrue())
+		Expect(received.Bytes(16)).To(Equal(toss.Bytes(16)))
+		_, ok = coins[3].Toss(toss.Signature(), nonce+1)
+		Expect(ok).To(BeFalse())
+	})
+	It("should give results within bounds", func() {
+		toss, ok := coins[0].Combine(shares, nonce)
+		Expect(ok).To(BeTrue())
+		Expect(toss.Bit()).To(BeNumerically("<=", 1))
+		for _, bound := range []uint64{1, 2, 3, 1000, 1<<63 + 1} {
+			Expect(toss.Int(bound)).To(BeNumerically("<", bound))
+		}
+	})
+	It("should give different results for different nonces", func() {
+		toss1, _ := coins[0].Combine(shares, nonce)
+		var next []*Share
+		for i := uint16(0); i < n; i++ {
+			if sh := coins[i].CreateShare(nonce + 1); sh != nil {
+				next = append(next, sh)
+			}
+		}
+		toss2, ok := coins[0].Combine(next, nonce+1)
+		Expect(ok).To(BeTrue())
+		Expect(toss1.Bytes(32)).NotTo(Equal(toss2.Bytes(32)))
+	})
+})
@@ -0,0 +1,182 @@
+package tss
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+)
+
+// ComplaintLength is the length of marshalled complaints.
+const ComplaintLength = 4 + bn256.SignatureLength
+
+// Complaint is a claim of the accuser that the dealer sent it an incorrect secret key.
+// The accuser reveals the secret shared with the dealer, so anyone can decrypt the secret key
+// and decide whether the dealer or the accuser is at fault.
+type Complaint struct {
+	dealer  uint16
+	accuser uint16
+	secret  p2p.SharedSecret
+}
+
+// NewComplaint creates a complaint of the accuser against the dealer.
+func NewComplaint(dealer, accuser uint16, accuserSK *p2p.SecretKey, dealerPK *p2p.PublicKey) *Complaint {
+	return &Complaint{
+		dealer:  dealer,
+		accuser: accuser,
+		secret:  p2p.NewSharedSecret(accuserSK, dealerPK),
+	}
+}
+
+// Dealer returns the pid of the accused dealer.
+func (c *Complaint) Dealer() uint16 {
+	return c.dealer
+}
+
+// Accuser returns the pid of the author of the complaint.
+func (c *Complaint) Accuser() uint16 {
+	return c.accuser
+}
+
+// Verify checks the complaint against the ThresholdKey dealt by the accused dealer and returns the pid of the faulty party.
+// The dealer is at fault if the revealed secret is correct, but the secret key of the accuser cannot be decrypted with it
+// or does not match the verification key. Otherwise the accuser is at fault.
+func (c *Complaint) Verify(tk *ThresholdKey, pks []*p2p.PublicKey) (uint16, error) {
+	if tk.dealer != c.dealer {
+		return 0, errors.New("threshold key dealt by a different dealer")
+	}
+	if int(c.dealer) >= len(pks) || int(c.accuser) >= len(pks) {
+		return 0, errors.New("pid out of range")
+	}
+	if int(c.accuser) >= len(tk.encSKs) {
+		return 0, errors.New("no encrypted secret key of the accuser")
+	}
+	if !p2p.VerifySharedSecret(pks[c.accuser], pks[c.dealer], c.secret) {
+		return c.accuser, nil
+	}
+	key, err := p2p.Key(c.secret)
+	if err != nil {
+		return c.accuser, nil
+	}
+	if tk.CheckSecretKey(c.accuser, key) {
+		return c.accuser, nil
+	}
+	return c.dealer, nil
+}
+
+// Marshal returns byte representation of the complaint in the following form
+// (1) dealer, 2 bytes as uint16
+// (2) accuser, 2 bytes as uint16
+// (3) marshalled shared secret
+func (c *Complaint) Marshal() []byte {
+	data := make([]byte, 4, ComplaintLength)
+	binary.LittleEndian.PutUint16(data[:2], c.dealer)
+	binary.LittleEndian.PutUint16(data[2:4], c.accuser)
+	return append(data, c.secret.Marshal()...)
+}
+
+// Unmarshal reads a complaint from its byte representation.
+func (c *Complaint) Unmarshal(data []byte) error {
+	if len(data) != ComplaintLength {
+		return errors.New("wrong length of complaint")
+	}
+	if _, err := c.secret.Unmarshal(data[4:]); err != nil {
+		return err
+	}
+	c.dealer = binary.LittleEndian.Uint16(data[:2])
+	c.accuser = binary.LittleEndian.Uint16(data[2:4])
+	return nil
+}
+
+// PublicComplaint is a claim of the accuser that the dealer sent it an incorrect secret key in a dealing created with EncryptPublic.
+// The accuser reveals the secret of the hybrid ciphertext of its secret key, so anyone can decrypt that secret key
+// and decide whether the dealer or the accuser is at fault. The secret is bound to the ciphertext and its position
+// in the dealing, so it discloses neither the p2p.SecretKey of the accuser nor anything that decrypts other messages.
+type PublicComplaint struct {
+	dealer  uint16
+	accuser uint16
+	secret  p2p.DecryptionSecret
+}
+
+// NewPublicComplaint creates a complaint of the accuser against the dealer of the given ThresholdKey.
+func NewPublicComplaint(tk *ThresholdKey, accuser uint16, accuserSK *p2p.SecretKey) (*PublicComplaint, error) {
+	if int(accuser) >= len(tk.encSKs) {
+		return nil, errors.New("no encrypted secret key of the accuser")
+	}
+	// A malformed ciphertext blames the dealer by itself, so the secret is left empty.
+	var secret p2p.DecryptionSecret
+	if p2p.WellFormed(tk.encSKs[accuser]) {
+		var err error
+		ad := dealingData(tk.dealer, accuser, tk.vks[accuser])
+		secret, err = p2p.NewDecryptionSecret(accuserSK, tk.encSKs[accuser], ad)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &PublicComplaint{
+		dealer:  tk.dealer,
+		accuser: accuser,
+		secret:  secret,
+	}, nil
+}
+
+// Dealer returns the pid of the accused dealer.
+func (c *PublicComplaint) Dealer() uint16 {
+	return c.dealer
+}
+
+// Accuser returns the pid of the author of the complaint.
+func (c *PublicComplaint) Accuser() uint16 {
+	return c.accuser
+}
+
+// Verify checks the complaint against the ThresholdKey dealt by the accused dealer and returns the pid of the faulty party.
+// The dealer is at fault if the ciphertext of the secret key of the accuser is malformed, or the revealed secret is correct,
+// but the secret key cannot be decrypted with it or does not match the verification key. Otherwise the accuser is at fault.
+func (c *PublicComplaint) Verify(tk *ThresholdKey, pks []*p2p.PublicKey) (uint16, error) {
+	if tk.dealer != c.dealer {
+		return 0, errors.New("threshold key dealt by a different dealer")
+	}
+	if int(c.dealer) >= len(pks) || int(c.accuser) >= len(pks) {
+		return 0, errors.New("pid out of range")
+	}
+	if int(c.accuser) >= len(tk.encSKs) {
+		return 0, errors.New("no encrypted secret key of the accuser")
+	}
+	if !p2p.WellFormed(tk.encSKs[c.accuser]) {
+		return c.dealer, nil
+	}
+	ad := dealingData(c.dealer, c.accuser, tk.vks[c.accuser])
+	if !p2p.VerifyDecryptionSecret(pks[c.accuser], tk.encSKs[c.accuser], ad, c.secret) {
+		return c.accuser, nil
+	}
+	if tk.CheckSecretKey(c.accuser, c.secret) {
+		return c.accuser, nil
+	}
+	return c.dealer, nil
+}
+
+// Marshal returns byte representation of the complaint in the following form
+// (1) dealer, 2 bytes as uint16
+// (2) accuser, 2 bytes as uint16
+// (3) marshalled decryption secret
+func (c *PublicComplaint) Marshal() []byte {
+	data := make([]byte, 4, ComplaintLength)
+	binary.LittleEndian.PutUint16(data[:2], c.dealer)
+	binary.LittleEndian.PutUint16(data[2:4], c.accuser)
+	return append(data, c.secret.Marshal()...)
+}
+
+// Unmarshal reads a complaint from its byte representation.
+func (c *PublicComplaint) Unmarshal(data []byte) error {
+	if len(data) != ComplaintLength {
+		return errors.New("wrong length of complaint")
+	}
+	if _, err := c.secret.Unmarshal(data[4:]); err != nil {
+		return err
+	}
+	c.dealer = binary.LittleEndian.Uint16(data[:2])
+	c.accuser = binary.LittleEndian.Uint16(data[2:4])
+	return nil
+}
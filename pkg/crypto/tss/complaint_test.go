@@ -0,0 +1,149 @@
+package tss_test
+
+import (
+	"bytes"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Complaint", func() {
+	var (
+		n, t, dealer, victim uint16
+		sKeys                []*p2p.SecretKey
+		pKeys                []*p2p.PublicKey
+		tk                   *ThresholdKey
+	)
+	BeforeEach(func() {
+		n, t, dealer, victim = 4, 2, 0, 1
+		sKeys = make([]*p2p.SecretKey, n)
+		pKeys = make([]*p2p.PublicKey, n)
+		for i := uint16(0); i < n; i++ {
+			pKeys[i], sKeys[i], _ = p2p.GenerateKeys()
+		}
+	})
+	deal := func(keys []encrypt.SymmetricKey) {
+		dealt, err := NewRandom(n, t).Encrypt(dealer, keys)
+		Expect(err).NotTo(HaveOccurred())
+		var ok bool
+		tk, ok, err = Decode(dealt.Encode(), dealer, 2, keys[2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	}
+	Context("Against an honest dealer", func() {
+		BeforeEach(func() {
+			keys, err := p2p.Keys(sKeys[dealer], pKeys, dealer)
+			Expect(err).NotTo(HaveOccurred())
+			deal(keys)
+		})
+		It("should blame the accuser", func() {
+			c := NewComplaint(dealer, victim, sKeys[victim], pKeys[dealer])
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(victim))
+		})
+		It("should blame an accuser revealing a wrong secret", func() {
+			c := NewComplaint(dealer, victim, sKeys[3], pKeys[dealer])
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(victim))
+		})
+	})
+	Context("Against a dealer who sent an incorrect share", func() {
+		BeforeEach(func() {
+			keys, err := p2p.Keys(sKeys[dealer], pKeys, dealer)
+			Expect(err).NotTo(HaveOccurred())
+			keys[victim], err = encrypt.NewSymmetricKey([]byte("not a shared secret"))
+			Expect(err).NotTo(HaveOccurred())
+			deal(keys)
+		})
+		It("should blame the dealer", func() {
+			c := NewComplaint(dealer, victim, sKeys[victim], pKeys[dealer])
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(dealer))
+		})
+		It("should still blame the dealer after marshalling and unmarshalling", func() {
+			c := new(Complaint)
+			Expect(c.Unmarshal(NewComplaint(dealer, victim, sKeys[victim], pKeys[dealer]).Marshal())).To(Succeed())
+			Expect(c.Dealer()).To(Equal(dealer))
+			Expect(c.Accuser()).To(Equal(victim))
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(dealer))
+		})
+		It("should not be verified against a key of a different dealer", func() {
+			c := NewComplaint(3, victim, sKeys[victim], pKeys[3])
+			_, err := c.Verify(tk, pKeys)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Against a dealer using public key encryption", func() {
+		dealPublic := func(keys []*p2p.PublicKey) {
+			dealt, err := NewRandom(n, t).EncryptPublic(dealer, keys)
+			Expect(err).NotTo(HaveOccurred())
+			var ok bool
+			tk, ok, err = Decode(dealt.Encode(), dealer, 2, sKeys[2])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+		It("should blame the accuser if the dealer is honest", func() {
+			dealPublic(pKeys)
+			c, err := NewPublicComplaint(tk, victim, sKeys[victim])
+			Expect(err).NotTo(HaveOccurred())
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(victim))
+		})
+		It("should blame an accuser revealing a wrong secret", func() {
+			dealPublic(pKeys)
+			c, err := NewPublicComplaint(tk, victim, sKeys[3])
+			Expect(err).NotTo(HaveOccurred())
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(victim))
+		})
+		It("should blame the dealer who sent an incorrect share, also after marshalling", func() {
+			keys := append([]*p2p.PublicKey{}, pKeys...)
+			keys[victim] = pKeys[3]
+			dealPublic(keys)
+			created, err := NewPublicComplaint(tk, victim, sKeys[victim])
+			Expect(err).NotTo(HaveOccurred())
+			c := new(PublicComplaint)
+			Expect(c.Unmarshal(created.Marshal())).To(Succeed())
+			Expect(c.Dealer()).To(Equal(dealer))
+			Expect(c.Accuser()).To(Equal(victim))
+			culprit, err := c.Verify(tk, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(culprit).To(Equal(dealer))
+		})
+		It("should blame the dealer and reveal nothing shared with another member whose key is used as the ephemeral key", func() {
+			other := uint16(3)
+			dealt, err := NewRandom(n, t).EncryptPublic(dealer, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			ct := dealt.EncryptedSecretKey(victim)
+			otherKey := pKeys[other].Marshal()
+			shared := p2p.NewSharedSecret(sKeys[victim], pKeys[other])
+			for _, ephemeral := range [][]byte{otherKey[4 : 4+64], otherKey[4+64:]} {
+				dealt.SetEncryptedSecretKey(victim, append(append([]byte{}, ephemeral...), ct[len(ephemeral):]...))
+				var ok bool
+				tk, ok, err = Decode(dealt.Encode(), dealer, victim, sKeys[victim])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				c, err := NewPublicComplaint(tk, victim, sKeys[victim])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bytes.Contains(c.Marshal(), shared.Marshal())).To(BeFalse())
+				culprit, err := c.Verify(tk, pKeys)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(culprit).To(Equal(dealer))
+			}
+		})
+	})
+	It("should not unmarshal data of a wrong length", func() {
+		Expect(new(Complaint).Unmarshal(make([]byte, ComplaintLength-1))).NotTo(Succeed())
+	})
+})
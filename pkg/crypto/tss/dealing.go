@@ -9,6 +9,7 @@ import (
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
 	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
 )
 
 // New returns a Threshold Signature Scheme based on given slice of coefficients.
@@ -51,14 +52,14 @@ func NewRandom(nProc, threshold uint16) *TSS {
 	return New(nProc, coeffs)
 }
 
-// Encrypt encrypts secretKeys of the given TSS
+// Encrypt encrypts secretKeys of the given TSS dealt by the dealer
 // using given a set of encryptionKeys and returns an (unowned)ThresholdKey.
-func (tss *TSS) Encrypt(encryptionKeys []encrypt.SymmetricKey) (*ThresholdKey, error) {
+func (tss *TSS) Encrypt(dealer uint16, encryptionKeys []encrypt.SymmetricKey) (*ThresholdKey, error) {
 	nProc := uint16(len(encryptionKeys))
 	encSKs := make([]encrypt.CipherText, nProc)
 
 	for i := uint16(0); i < nProc; i++ {
-		encSK, err := encryptionKeys[i].Encrypt(tss.sks[i].Marshal())
+		encSK, err := encryptionKeys[i].EncryptWithData(tss.sks[i].Marshal(), dealingData(dealer, i, tss.vks[i]))
 		if err != nil {
 			return nil, err
 		}
@@ -73,6 +74,49 @@ func (tss *TSS) Encrypt(encryptionKeys []encrypt.SymmetricKey) (*ThresholdKey, e
 	}, nil
 }
 
+// EncryptPublic encrypts secretKeys of the given TSS to the public keys of their owners
+// and returns an (unowned)ThresholdKey. Unlike Encrypt, it does not need keys shared with the recipients,
+// who decrypt their secret keys with their own p2p.SecretKey.
+// Such dealings are disputed with a PublicComplaint instead of a Complaint, since there is no shared secret to reveal.
+func (tss *TSS) EncryptPublic(dealer uint16, publicKeys []*p2p.PublicKey) (*ThresholdKey, error) {
+	nProc := uint16(len(publicKeys))
+	encSKs := make([]encrypt.CipherText, nProc)
+
+	for i := uint16(0); i < nProc; i++ {
+		encSK, err := publicKeys[i].EncryptWithData(tss.sks[i].Marshal(), dealingData(dealer, i, tss.vks[i]))
+		if err != nil {
+			return nil, err
+		}
+		encSKs[i] = encSK
+	}
+
+	return &ThresholdKey{
+		threshold: tss.threshold,
+		globalVK:  tss.globalVK,
+		vks:       tss.vks,
+		encSKs:    encSKs,
+	}, nil
+}
+
+// Decrypter decrypts secret keys of a ThresholdKey. It is implemented by
+// encrypt.SymmetricKey for dealings created with Encrypt and by p2p.SecretKey for dealings created with EncryptPublic.
+type Decrypter interface {
+	DecryptWithData(ct encrypt.CipherText, ad []byte) ([]byte, error)
+}
+
+// dealingData returns the associated data of the ciphertext of the secret key with the given index, in the following form
+// (1) label "tss-dealing"
+// (2) dealer, 2 bytes as uint16
+// (3) index of the secret key, 2 bytes as uint16
+// (4) marshalled verification key of the secret key
+// It binds the ciphertext to its position in the dealing, so it cannot be replayed by another dealer or for another owner.
+func dealingData(dealer, index uint16, vk *bn256.VerificationKey) []byte {
+	data := append([]byte("tss-dealing"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(data[len(data)-4:], dealer)
+	binary.LittleEndian.PutUint16(data[len(data)-2:], index)
+	return append(data, vk.Marshal()...)
+}
+
 // Encode returns a byte representation of the given (unowned) ThresholdKey in the following form
 // (1) threshold, 2 bytes as uint16
 // (2) length of marshalled globalVK, 4 bytes as uint32
@@ -116,79 +160,92 @@ func (tk *ThresholdKey) Encode() []byte {
 // (1) decoded ThresholdKey,
 // (2) whether the owner's secretKey is correctly encoded and matches corresponding verification key,
 // (3) an error in decoding (excluding errors obtained while decoding owners secret key),
-func Decode(data []byte, dealer, owner uint16, decryptionKey encrypt.SymmetricKey) (*ThresholdKey, bool, error) {
+func Decode(data []byte, dealer, owner uint16, decryptionKey Decrypter) (*ThresholdKey, bool, error) {
+	tk, err := decodeDealing(data, dealer)
+	if err != nil {
+		return nil, false, err
+	}
+	if int(owner) >= len(tk.vks) {
+		return nil, false, errors.New("Decoding key failed. Owner out of range")
+	}
+	sk, err := tk.decryptSecretKey(owner, decryptionKey)
+	tk.owner = owner
+	tk.sk = sk
+	return tk, (err == nil), nil
+}
+
+// decodeDealing parses encoded ThresholdKey obtained from the dealer without decrypting any secret key.
+func decodeDealing(data []byte, dealer uint16) (*ThresholdKey, error) {
 	ind := 0
 	dataTooShort := errors.New("Decoding key failed. Given bytes slice is too short")
 	if len(data) < ind+2 {
-		return nil, false, dataTooShort
+		return nil, dataTooShort
 	}
 	threshold := binary.LittleEndian.Uint16(data[:(ind + 2)])
 	ind += 2
 
 	if len(data) < ind+4 {
-		return nil, false, dataTooShort
+		return nil, dataTooShort
 	}
 	gvkLen := int(binary.LittleEndian.Uint32(data[ind:(ind + 4)]))
 	ind += 4
 	if len(data) < ind+gvkLen {
-		return nil, false, dataTooShort
+		return nil, dataTooShort
 	}
 	globalVK, err := new(bn256.VerificationKey).Unmarshal(data[ind:(ind + gvkLen)])
 	if err != nil {
-		return nil, false, errors.New("unmarshal of globalVK failed")
+		return nil, errors.New("unmarshal of globalVK failed")
 	}
 	ind += gvkLen
 
 	if len(data) < ind+4 {
-		return nil, false, dataTooShort
+		return nil, dataTooShort
 	}
 	nProcesses := uint16(binary.LittleEndian.Uint32(data[ind:(ind + 4)]))
 	ind += 4
 	vks := make([]*bn256.VerificationKey, nProcesses)
 	for i := range vks {
 		if len(data) < ind+4 {
-			return nil, false, dataTooShort
+			return nil, dataTooShort
 		}
 		vkLen := int(binary.LittleEndian.Uint32(data[ind:(ind + 4)]))
 		ind += 4
 		if len(data) < ind+vkLen {
-			return nil, false, dataTooShort
+			return nil, dataTooShort
 		}
 		vks[i], err = new(bn256.VerificationKey).Unmarshal(data[ind:(ind + vkLen)])
 		if err != nil {
-			return nil, false, errors.New("unmarshal of vk failed")
+			return nil, errors.New("unmarshal of vk failed")
 		}
 		ind += vkLen
 	}
 	encSKs := make([]encrypt.CipherText, nProcesses)
 	for i := range encSKs {
 		if len(data) < ind+4 {
-			return nil, false, dataTooShort
+			return nil, dataTooShort
 		}
 		skLen := int(binary.LittleEndian.Uint32(data[ind:(ind + 4)]))
 		ind += 4
 		if len(data) < ind+skLen {
-			return nil, false, dataTooShort
+			return nil, dataTooShort
 		}
 		encSKs[i] = data[ind:(ind + skLen)]
 		ind += skLen
 	}
 
-	sk, err := decryptSecretKey(encSKs[owner], vks[owner], decryptionKey)
-
 	return &ThresholdKey{
 		dealer:    dealer,
-		owner:     owner,
 		threshold: threshold,
 		globalVK:  globalVK,
 		vks:       vks,
 		encSKs:    encSKs,
-		sk:        sk,
-	}, (err == nil), nil
+	}, nil
 }
 
-func decryptSecretKey(data []byte, vk *bn256.VerificationKey, decryptionKey encrypt.SymmetricKey) (*bn256.SecretKey, error) {
-	decrypted, err := decryptionKey.Decrypt(data)
+// decryptSecretKey decrypts the secret key with the given index and checks it against the corresponding verification key.
+func (tk *ThresholdKey) decryptSecretKey(index uint16, decryptionKey Decrypter) (*bn256.SecretKey, error) {
+	vk := tk.vks[index]
+	decrypted, err := decryptionKey.DecryptWithData(tk.encSKs[index], dealingData(tk.dealer, index, vk))
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +262,7 @@ func decryptSecretKey(data []byte, vk *bn256.VerificationKey, decryptionKey encr
 }
 
 // CheckSecretKey checks whether the secret key of the given pid is correct.
-func (tk *ThresholdKey) CheckSecretKey(pid uint16, decryptionKey encrypt.SymmetricKey) bool {
-	_, err := decryptSecretKey(tk.encSKs[pid], tk.vks[pid], decryptionKey)
+func (tk *ThresholdKey) CheckSecretKey(pid uint16, decryptionKey Decrypter) bool {
+	_, err := tk.decryptSecretKey(pid, decryptionKey)
 	return err == nil
 }
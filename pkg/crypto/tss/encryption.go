@@ -0,0 +1,226 @@
+package tss
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+// CipherText is a message encrypted to all the holders of a threshold key.
+// It can be decrypted only after threshold many of them publish their decryption shares.
+//
+// For a random r, the ciphertext consists of
+// (1) u = r*g, where g is the generator of G2,
+// (2) the message encrypted with a symmetric key derived from r*globalVK,
+// (3) w = r*hash(u, encrypted message), which proves that the ciphertext was created honestly.
+type CipherText struct {
+	u       *bn256.VerificationKey
+	w       *bn256.Signature
+	payload encrypt.CipherText
+}
+
+// DecryptionShare is a share of the decryption key of a ciphertext created by one of the key holders.
+type DecryptionShare struct {
+	owner uint16
+	d     *bn256.VerificationKey
+}
+
+// Owner returns owner's PID of this decryption share.
+func (ds *DecryptionShare) Owner() uint16 {
+	return ds.owner
+}
+
+// GlobalVK returns the global verification key of the threshold key.
+// It is also the public key used for encrypting messages to all the holders of the threshold key.
+func (tk *ThresholdKey) GlobalVK() *bn256.VerificationKey {
+	return tk.globalVK
+}
+
+// Encrypt encrypts msg to all the holders of the threshold key with the given global verification key.
+func Encrypt(globalVK *bn256.VerificationKey, msg []byte) (*CipherText, error) {
+	r, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+	key, err := encrypt.NewSymmetricKey(bn256.MulVerificationKey(globalVK, r).Marshal())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := key.Encrypt(msg)
+	if err != nil {
+		return nil, err
+	}
+	ct := &CipherText{
+		u:       bn256.NewVerificationKey(r),
+		payload: payload,
+	}
+	ct.w = bn256.NewSecretKey(r).Sign(ct.tag())
+	return ct, nil
+}
+
+// Encrypt encrypts msg to all the holders of the threshold key.
+func (tk *ThresholdKey) Encrypt(msg []byte) (*CipherText, error) {
+	return Encrypt(tk.globalVK, msg)
+}
+
+// tag returns the data signed by w.
+func (ct *CipherText) tag() []byte {
+	data := []byte("tss-enc")
+	data = append(data, ct.u.Marshal()...)
+	return append(data, ct.payload...)
+}
+
+// Verify checks whether the ciphertext was created honestly.
+// Decryption shares should never be created for ciphertexts that fail this check.
+func (ct *CipherText) Verify() bool {
+	return ct.u.Verify(ct.w, ct.tag())
+}
+
+// CreateDecryptionShare creates a DecryptionShare of the given ciphertext.
+// It returns false if the ciphertext is invalid.
+func (tk *ThresholdKey) CreateDecryptionShare(ct *CipherText) (*DecryptionShare, bool) {
+	if !ct.Verify() {
+		return nil, false
+	}
+	return &DecryptionShare{
+		owner: tk.owner,
+		d:     bn256.MulVerificationKey(ct.u, new(big.Int).SetBytes(tk.sk.Marshal())),
+	}, true
+}
+
+// CreateDecryptionShare creates a DecryptionShare of the given ciphertext if the holder of
+// the weak threshold key is a share provider. Else, or if the ciphertext is invalid, it returns false.
+func (wtk *WeakThresholdKey) CreateDecryptionShare(ct *CipherText) (*DecryptionShare, bool) {
+	if !wtk.shareProviders[wtk.owner] {
+		return nil, false
+	}
+	return wtk.ThresholdKey.CreateDecryptionShare(ct)
+}
+
+// VerifyDecryptionShare verifies whether the given decryption share of a valid ciphertext is correct.
+// The share of the i-th holder is correct if it is equal to r*sk_i, which is checked using the pairing
+// e(w, vk_i) = e(hash(u, encrypted message), share).
+func (tk *ThresholdKey) VerifyDecryptionShare(ct *CipherText, ds *DecryptionShare) bool {
+	if int(ds.owner) >= len(tk.vks) {
+		return false
+	}
+	return ds.d.VerifyWithBase(ct.w, tk.vks[ds.owner], ct.tag())
+}
+
+// CombineDecryptionShares verifies the given decryption shares of the ciphertext, combines threshold many valid ones
+// and decrypts it. It returns the decrypted message, and the owners of invalid shares that provided no valid share.
+func (tk *ThresholdKey) CombineDecryptionShares(ct *CipherText, shares []*DecryptionShare) ([]byte, []uint16, error) {
+	if !ct.Verify() {
+		return nil, nil, errors.New("invalid ciphertext")
+	}
+	var valid []*DecryptionShare
+	owners := newShareOwners()
+	for _, ds := range shares {
+		if uint16(len(valid)) >= tk.threshold {
+			break
+		}
+		if ds == nil || owners.valid[ds.owner] {
+			continue
+		}
+		if !tk.VerifyDecryptionShare(ct, ds) {
+			if int(ds.owner) < len(tk.vks) {
+				owners.bad[ds.owner] = true
+			}
+			continue
+		}
+		owners.valid[ds.owner] = true
+		valid = append(valid, ds)
+	}
+	misbehaving := owners.misbehaving()
+	if uint16(len(valid)) < tk.threshold {
+		return nil, misbehaving, errors.New("not enough valid decryption shares")
+	}
+	msg, err := tk.combineDecryptionShares(ct, valid)
+	return msg, misbehaving, err
+}
+
+// combineDecryptionShares decrypts the ciphertext using exactly threshold many valid shares of distinct owners.
+func (tk *ThresholdKey) combineDecryptionShares(ct *CipherText, shares []*DecryptionShare) ([]byte, error) {
+	var points []int64
+	for _, ds := range shares {
+		points = append(points, int64(ds.owner))
+	}
+
+	ds := make([]*bn256.VerificationKey, len(shares))
+	coeffs := make([]*big.Int, len(shares))
+
+	var wg sync.WaitGroup
+	for i, sh := range shares {
+		wg.Add(1)
+		go func(i int, sh *DecryptionShare) {
+			defer wg.Done()
+			ds[i] = sh.d
+			coeffs[i] = lagrange(points, int64(sh.owner))
+		}(i, sh)
+	}
+	wg.Wait()
+
+	key, err := encrypt.NewSymmetricKey(bn256.MultiMulVerificationKeys(ds, coeffs).Marshal())
+	if err != nil {
+		return nil, err
+	}
+	return key.Decrypt(ct.payload)
+}
+
+// Marshal returns byte representation of the given ciphertext in the following form
+// (1) u, compressed
+// (2) w, compressed
+// (3) encrypted message
+func (ct *CipherText) Marshal() []byte {
+	data := ct.u.MarshalCompressed()
+	data = append(data, ct.w.MarshalCompressed()...)
+	return append(data, ct.payload...)
+}
+
+// Unmarshal reads a ciphertext from its byte representation.
+func (ct *CipherText) Unmarshal(data []byte) error {
+	if len(data) < bn256.CompressedVerificationKeyLength+bn256.CompressedSignatureLength {
+		return errors.New("given data is too short")
+	}
+	u, err := new(bn256.VerificationKey).UnmarshalCompressed(data[:bn256.CompressedVerificationKeyLength])
+	if err != nil {
+		return err
+	}
+	data = data[bn256.CompressedVerificationKeyLength:]
+	w, err := new(bn256.Signature).UnmarshalCompressed(data[:bn256.CompressedSignatureLength])
+	if err != nil {
+		return err
+	}
+	ct.u = u
+	ct.w = w
+	ct.payload = append(encrypt.CipherText{}, data[bn256.CompressedSignatureLength:]...)
+	return nil
+}
+
+// Marshal returns byte representation of the given decryption share in the following form
+// (1) owner, 2 bytes as uint16
+// (2) share of the decryption key, compressed
+func (ds *DecryptionShare) Marshal() []byte {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[:2], ds.owner)
+	return append(data, ds.d.MarshalCompressed()...)
+}
+
+// Unmarshal reads a decryption share from its byte representation.
+func (ds *DecryptionShare) Unmarshal(data []byte) error {
+	if len(data) != 2+bn256.CompressedVerificationKeyLength {
+		return errors.New("wrong length of decryption share")
+	}
+	d, err := new(bn256.VerificationKey).UnmarshalCompressed(data[2:])
+	if err != nil {
+		return err
+	}
+	ds.owner = binary.LittleEndian.Uint16(data[:2])
+	ds.d = d
+	return nil
+}
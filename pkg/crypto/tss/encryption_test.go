@@ -0,0 +1,137 @@
+package tss_test
+
+import (
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Threshold encryption", func() {
+	var (
+		n, t, dealer uint16
+		msg          []byte
+		tks          []*ThresholdKey
+		ct           *CipherText
+		shares       []*DecryptionShare
+	)
+	BeforeEach(func() {
+		n, t, dealer = 7, 3, 2
+		sKeys := make([]*p2p.SecretKey, n)
+		pKeys := make([]*p2p.PublicKey, n)
+		for i := uint16(0); i < n; i++ {
+			pKeys[i], sKeys[i], _ = p2p.GenerateKeys()
+		}
+		p2pKeys := make([][]encrypt.SymmetricKey, n)
+		for i := uint16(0); i < n; i++ {
+			p2pKeys[i], _ = p2p.Keys(sKeys[i], pKeys, i)
+		}
+		tk, err := NewRandom(n, t).Encrypt(dealer, p2pKeys[dealer])
+		Expect(err).NotTo(HaveOccurred())
+		encoded := tk.Encode()
+		tks = make([]*ThresholdKey, n)
+		for i := uint16(0); i < n; i++ {
+			tks[i], _, err = Decode(encoded, dealer, i, p2pKeys[i][dealer])
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		msg = []byte("transfer 100 coins to Alice")
+		ct, err = Encrypt(tks[0].GlobalVK(), msg)
+		Expect(err).NotTo(HaveOccurred())
+		shares = make([]*DecryptionShare, n)
+		for i := uint16(0); i < n; i++ {
+			var ok bool
+			shares[i], ok = tks[i].CreateDecryptionShare(ct)
+			Expect(ok).To(BeTrue())
+		}
+	})
+	It("should produce valid ciphertexts", func() {
+		Expect(ct.Verify()).To(BeTrue())
+	})
+	It("should verify decryption shares", func() {
+		for i := uint16(0); i < n; i++ {
+			Expect(tks[0].VerifyDecryptionShare(ct, shares[i])).To(BeTrue())
+		}
+	})
+	It("should reject decryption shares of a different ciphertext", func() {
+		ct2, err := tks[1].Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tks[0].VerifyDecryptionShare(ct2, shares[1])).To(BeFalse())
+	})
+	It("should reject decryption shares attributed to a different owner", func() {
+		share := new(DecryptionShare)
+		data := shares[1].Marshal()
+		data[0] = 2
+		Expect(share.Unmarshal(data)).To(Succeed())
+		Expect(tks[0].VerifyDecryptionShare(ct, share)).To(BeFalse())
+	})
+	It("should decrypt using threshold many shares", func() {
+		result, bad, err := tks[0].CombineDecryptionShares(ct, shares[:t])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bad).To(BeEmpty())
+		Expect(result).To(Equal(msg))
+		result, _, err = tks[3].CombineDecryptionShares(ct, shares[n-t:])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(msg))
+	})
+	It("should not decrypt using fewer than threshold shares", func() {
+		_, _, err := tks[0].CombineDecryptionShares(ct, shares[:t-1])
+		Expect(err).To(HaveOccurred())
+	})
+	It("should skip invalid and duplicated shares and report their owners", func() {
+		ct2, err := tks[1].Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		wrong, ok := tks[3].CreateDecryptionShare(ct2)
+		Expect(ok).To(BeTrue())
+		mixed := []*DecryptionShare{wrong, shares[0], shares[0], nil, shares[1], shares[2]}
+		result, bad, err := tks[0].CombineDecryptionShares(ct, mixed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(msg))
+		Expect(bad).To(Equal([]uint16{3}))
+	})
+	It("should not report an owner whose valid share follows an invalid one", func() {
+		ct2, err := tks[1].Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		wrong, ok := tks[1].CreateDecryptionShare(ct2)
+		Expect(ok).To(BeTrue())
+		mixed := []*DecryptionShare{wrong, shares[0], shares[1], shares[2]}
+		result, bad, err := tks[0].CombineDecryptionShares(ct, mixed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(msg))
+		Expect(bad).To(BeEmpty())
+	})
+	It("should not decrypt when too few shares are valid", func() {
+		ct2, err := tks[1].Encrypt(msg)
+		Expect(err).NotTo(HaveOccurred())
+		wrong, _ := tks[3].CreateDecryptionShare(ct2)
+		_, bad, err := tks[0].CombineDecryptionShares(ct, []*DecryptionShare{shares[0], wrong, shares[1]})
+		Expect(err).To(HaveOccurred())
+		Expect(bad).To(Equal([]uint16{3}))
+	})
+	It("should not create decryption shares for a tampered ciphertext", func() {
+		data := ct.Marshal()
+		data[len(data)-1] ^= 1
+		tampered := new(CipherText)
+		Expect(tampered.Unmarshal(data)).To(Succeed())
+		Expect(tampered.Verify()).To(BeFalse())
+		_, ok := tks[0].CreateDecryptionShare(tampered)
+		Expect(ok).To(BeFalse())
+	})
+	It("should marshal and unmarshal ciphertexts and shares", func() {
+		ct2 := new(CipherText)
+		Expect(ct2.Unmarshal(ct.Marshal())).To(Succeed())
+		Expect(ct2.Verify()).To(BeTrue())
+		shares2 := make([]*DecryptionShare, t)
+		for i := range shares2 {
+			shares2[i] = new(DecryptionShare)
+			Expect(shares2[i].Unmarshal(shares[i].Marshal())).To(Succeed())
+			Expect(shares2[i].Owner()).To(Equal(uint16(i)))
+			Expect(tks[0].VerifyDecryptionShare(ct2, shares2[i])).To(BeTrue())
+		}
+		result, _, err := tks[0].CombineDecryptionShares(ct2, shares2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(msg))
+	})
+})
@@ -0,0 +1,13 @@
+package tss
+
+import "gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+
+// EncryptedSecretKey returns the encrypted secret key of the given pid.
+func (tk *ThresholdKey) EncryptedSecretKey(pid uint16) encrypt.CipherText {
+	return tk.encSKs[pid]
+}
+
+// SetEncryptedSecretKey replaces the encrypted secret key of the given pid, to simulate a byzantine dealer.
+func (tk *ThresholdKey) SetEncryptedSecretKey(pid uint16, ct encrypt.CipherText) {
+	tk.encSKs[pid] = ct
+}
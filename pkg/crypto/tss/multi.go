@@ -20,6 +20,8 @@ import (
 //  (3) the thresholdKeys have the same owner
 //
 // The resulting WeakThresholdKey has undefined dealer and encSKs.
+// If the secret key of any of the given thresholdKeys is missing,
+// the secret key of the result is missing as well.
 func CreateWTK(tks []*ThresholdKey, shareProviders map[uint16]bool) *WeakThresholdKey {
 	n := len(tks[0].vks)
 
@@ -33,13 +35,22 @@ func CreateWTK(tks []*ThresholdKey, shareProviders map[uint16]bool) *WeakThresho
 
 	result.shareProviders = shareProviders
 
+	complete := true
 	for _, tk := range tks {
-		result.sk = bn256.AddSecretKeys(result.sk, tk.sk)
+		if tk.sk == nil {
+			complete = false
+		}
+		if complete {
+			result.sk = bn256.AddSecretKeys(result.sk, tk.sk)
+		}
 		result.globalVK = bn256.AddVerificationKeys(result.globalVK, tk.globalVK)
 		for i, vk := range tk.vks {
 			result.vks[i] = bn256.AddVerificationKeys(result.vks[i], vk)
 		}
 	}
+	if !complete {
+		result.sk = nil
+	}
 	return result
 }
 
@@ -78,7 +89,7 @@ func SeededWTK(nProc, pid uint16, seed int64, shareProviders map[uint16]bool) *W
 	p2pKeys, _ := p2p.Keys(sKeys[dealer], pKeys, dealer)
 
 	gtk := New(nProc, coeffs)
-	tkEncrypted, _ := gtk.Encrypt(p2pKeys)
+	tkEncrypted, _ := gtk.Encrypt(dealer, p2pKeys)
 	tk, _, _ := Decode(tkEncrypted.Encode(), dealer, pid, p2pKeys[pid])
 
 	if shareProviders == nil {
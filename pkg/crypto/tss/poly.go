@@ -14,7 +14,9 @@ func lagrange(points []int64, x int64) *big.Int {
 			continue
 		}
 		num.Mul(num, big.NewInt(0-p-1))
+		num.Mod(num, bn256.Order)
 		den.Mul(den, big.NewInt(x-p))
+		den.Mod(den, bn256.Order)
 	}
 	den.ModInverse(den, bn256.Order)
 	num.Mul(num, den)
@@ -0,0 +1,122 @@
+package tss
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+)
+
+// NewPublic returns a ThresholdKey without a secret key, that can only be used for verification.
+// It allows members of a new committee to check and combine resharings of a key they do not hold.
+func NewPublic(threshold uint16, globalVK *bn256.VerificationKey, vks []*bn256.VerificationKey) *ThresholdKey {
+	return &ThresholdKey{
+		threshold: threshold,
+		globalVK:  globalVK,
+		vks:       vks,
+	}
+}
+
+// VerificationKeys returns the verification keys of all the parties holding shares of the key.
+func (tk *ThresholdKey) VerificationKeys() []*bn256.VerificationKey {
+	return tk.vks
+}
+
+// Reshare returns a TSS dealing the secret key of the owner to a new committee of nProc parties with the given threshold.
+// It should be encrypted for the new committee and sent to its members, just like a regular dealing.
+// Any threshold many correct resharings of the key can be combined into a new key with the same global verification key.
+func (tk *ThresholdKey) Reshare(nProc, threshold uint16) (*TSS, error) {
+	if tk.sk == nil {
+		return nil, errors.New("no secret key to reshare")
+	}
+	if threshold == 0 || threshold > nProc {
+		return nil, errors.New("wrong threshold")
+	}
+	coeffs := make([]*big.Int, threshold)
+	for i := uint16(0); i+1 < threshold; i++ {
+		c, err := rand.Int(rand.Reader, bn256.Order)
+		if err != nil {
+			return nil, err
+		}
+		coeffs[i] = c
+	}
+	coeffs[threshold-1] = new(big.Int).SetBytes(tk.sk.Marshal())
+	return New(nProc, coeffs), nil
+}
+
+// VerifyReshare checks whether the dealing, decoded by a member of the new committee, is a correct resharing of the share of dealer.
+// It only uses public data, so tk may lack the secret key. Whether the secret key of the new member is correct
+// is reported by Decode, and can be proven with a Complaint.
+func (tk *ThresholdKey) VerifyReshare(dealer uint16, dealing *ThresholdKey) bool {
+	if int(dealer) >= len(tk.vks) || dealing.dealer != dealer {
+		return false
+	}
+	if subtle.ConstantTimeCompare(dealing.globalVK.Marshal(), tk.vks[dealer].Marshal()) != 1 {
+		return false
+	}
+	return dealing.VerifyPublicKeys()
+}
+
+// CombineReshares combines correct resharings of tk, all decoded by the same member of the new committee,
+// into a ThresholdKey of that member with the same global verification key as tk.
+// Only the first threshold of the given dealings are used and they should be verified with VerifyReshare beforehand.
+// The resulting ThresholdKey has undefined dealer and encSKs.
+func (tk *ThresholdKey) CombineReshares(dealings []*ThresholdKey) (*ThresholdKey, error) {
+	if uint16(len(dealings)) > tk.threshold {
+		dealings = dealings[:tk.threshold]
+	}
+	if uint16(len(dealings)) != tk.threshold {
+		return nil, errors.New("not enough resharings")
+	}
+	first := dealings[0]
+	nProc := len(first.vks)
+	points := make([]int64, len(dealings))
+	seen := map[uint16]bool{}
+	for i, dealing := range dealings {
+		if seen[dealing.dealer] {
+			return nil, errors.New("duplicate resharing")
+		}
+		seen[dealing.dealer] = true
+		if dealing.owner != first.owner || dealing.threshold != first.threshold || len(dealing.vks) != nProc {
+			return nil, errors.New("resharings for different keys")
+		}
+		if dealing.sk == nil {
+			return nil, errors.New("missing secret key in resharing")
+		}
+		points[i] = int64(dealing.dealer)
+	}
+
+	coeffs := make([]*big.Int, len(dealings))
+	globalVKs := make([]*bn256.VerificationKey, len(dealings))
+	secret := new(big.Int)
+	for i, dealing := range dealings {
+		coeffs[i] = lagrange(points, points[i])
+		globalVKs[i] = dealing.globalVK
+		term := new(big.Int).SetBytes(dealing.sk.Marshal())
+		secret.Add(secret, term.Mul(term, coeffs[i]))
+	}
+	secret.Mod(secret, bn256.Order)
+
+	if subtle.ConstantTimeCompare(bn256.MultiMulVerificationKeys(globalVKs, coeffs).Marshal(), tk.globalVK.Marshal()) != 1 {
+		return nil, errors.New("resharings do not match the global verification key")
+	}
+
+	vks := make([]*bn256.VerificationKey, nProc)
+	column := make([]*bn256.VerificationKey, len(dealings))
+	for j := range vks {
+		for i, dealing := range dealings {
+			column[i] = dealing.vks[j]
+		}
+		vks[j] = bn256.MultiMulVerificationKeys(column, coeffs)
+	}
+
+	return &ThresholdKey{
+		owner:     first.owner,
+		threshold: first.threshold,
+		globalVK:  tk.globalVK,
+		vks:       vks,
+		sk:        bn256.NewSecretKey(secret),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package tss_test
+
+import (
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resharing", func() {
+	var (
+		n, t, newN, newT uint16
+		oldKeys          []*ThresholdKey
+		newKeys          [][]encrypt.SymmetricKey
+		newSKs           []*p2p.SecretKey
+		newPKs           []*p2p.PublicKey
+		msg              []byte
+	)
+	BeforeEach(func() {
+		n, t, newN, newT = 4, 2, 5, 3
+		msg = []byte("xyz")
+		oldSKs := make([]*p2p.SecretKey, n)
+		oldPKs := make([]*p2p.PublicKey, n)
+		for i := uint16(0); i < n; i++ {
+			oldPKs[i], oldSKs[i], _ = p2p.GenerateKeys()
+		}
+		dealerKeys, err := p2p.Keys(oldSKs[0], oldPKs, 0)
+		Expect(err).NotTo(HaveOccurred())
+		dealt, err := NewRandom(n, t).Encrypt(0, dealerKeys)
+		Expect(err).NotTo(HaveOccurred())
+		encoded := dealt.Encode()
+		oldKeys = make([]*ThresholdKey, n)
+		for i := uint16(0); i < n; i++ {
+			keys, err := p2p.Keys(oldSKs[i], oldPKs, i)
+			Expect(err).NotTo(HaveOccurred())
+			oldKeys[i], _, err = Decode(encoded, 0, i, keys[0])
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		// members of the new committee share keys with all the old members, followed by each other
+		newSKs = make([]*p2p.SecretKey, n+newN)
+		newPKs = make([]*p2p.PublicKey, n+newN)
+		for i := range newSKs {
+			newPKs[i], newSKs[i], _ = p2p.GenerateKeys()
+		}
+		newKeys = make([][]encrypt.SymmetricKey, n+newN)
+		for i := range newKeys {
+			newKeys[i], err = p2p.Keys(newSKs[i], newPKs, uint16(i))
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+	// reshare returns the resharings by the given old members, decoded by every new member.
+	reshare := func(dealers []uint16) [][]*ThresholdKey {
+		result := make([][]*ThresholdKey, newN)
+		for _, dealer := range dealers {
+			dealing, err := oldKeys[dealer].Reshare(newN, newT)
+			Expect(err).NotTo(HaveOccurred())
+			dealt, err := dealing.Encrypt(dealer, newKeys[dealer][n:])
+			Expect(err).NotTo(HaveOccurred())
+			encoded := dealt.Encode()
+			for j := uint16(0); j < newN; j++ {
+				tk, ok, err := Decode(encoded, dealer, j, newKeys[n+j][dealer])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				result[j] = append(result[j], tk)
+			}
+		}
+		return result
+	}
+	It("should produce keys with the same global verification key", func() {
+		dealings := reshare([]uint16{1, 3})
+		public := NewPublic(t, oldKeys[0].GlobalVK(), oldKeys[0].VerificationKeys())
+		shares := make([]*Share, newN)
+		var newTK *ThresholdKey
+		for j := uint16(0); j < newN; j++ {
+			Expect(public.VerifyReshare(3, dealings[j][0])).To(BeFalse())
+			Expect(public.VerifyReshare(1, dealings[j][0])).To(BeTrue())
+			Expect(public.VerifyReshare(3, dealings[j][1])).To(BeTrue())
+			tk, err := public.CombineReshares(dealings[j])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tk.GlobalVK().Marshal()).To(Equal(oldKeys[0].GlobalVK().Marshal()))
+			Expect(tk.Threshold()).To(Equal(newT))
+			Expect(tk.NProc()).To(Equal(newN))
+			Expect(tk.VerifyPublicKeys()).To(BeTrue())
+			Expect(tk.VerifySecretKey()).To(BeNil())
+			shares[j] = tk.CreateShare(msg)
+			newTK = tk
+		}
+		for j := uint16(0); j < newN; j++ {
+			Expect(newTK.VerifyShare(shares[j], msg)).To(BeTrue())
+		}
+		sgn, ok := newTK.CombineShares(shares[newN-newT:])
+		Expect(ok).To(BeTrue())
+		Expect(oldKeys[0].VerifySignature(sgn, msg)).To(BeTrue())
+		oldSgn, ok := oldKeys[0].CombineShares([]*Share{oldKeys[0].CreateShare(msg), oldKeys[2].CreateShare(msg)})
+		Expect(ok).To(BeTrue())
+		Expect(sgn.Marshal()).To(Equal(oldSgn.Marshal()))
+	})
+	It("should not combine too few resharings", func() {
+		dealings := reshare([]uint16{2})
+		_, err := oldKeys[0].CombineReshares(dealings[0])
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject resharing of a different secret", func() {
+		dealt, err := NewRandom(newN, newT).Encrypt(2, newKeys[2][n:])
+		Expect(err).NotTo(HaveOccurred())
+		dealing, _, err := Decode(dealt.Encode(), 2, 0, newKeys[n][2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dealing.VerifyPublicKeys()).To(BeTrue())
+		Expect(oldKeys[0].VerifyReshare(2, dealing)).To(BeFalse())
+		dealings := reshare([]uint16{1})
+		_, err = oldKeys[0].CombineReshares([]*ThresholdKey{dealings[0][0], dealing})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject duplicate resharings", func() {
+		dealings := reshare([]uint16{1})
+		_, err := oldKeys[0].CombineReshares([]*ThresholdKey{dealings[0][0], dealings[0][0]})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,8 +1,11 @@
 package tss
 
 import (
+	"crypto/rand"
 	"encoding/binary"
 	"errors"
+	"math/big"
+	"sort"
 	"sync"
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
@@ -18,6 +21,15 @@ func (sh *Share) Marshal() []byte {
 	return data
 }
 
+// MarshalCompressed returns byte representation of the given signature share
+// in the same form as Marshal, but with the signature compressed.
+func (sh *Share) MarshalCompressed() []byte {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[:2], sh.owner)
+	data = append(data, sh.sgn.MarshalCompressed()...)
+	return data
+}
+
 // Unmarshal reads a signature share from its byte representation.
 func (sh *Share) Unmarshal(data []byte) error {
 	if len(data) < 2 {
@@ -34,11 +46,40 @@ func (sh *Share) Unmarshal(data []byte) error {
 	return nil
 }
 
+// UnmarshalCompressed reads a signature share from its byte representation created with MarshalCompressed.
+func (sh *Share) UnmarshalCompressed(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("given data is too short")
+	}
+	sgn, err := new(bn256.Signature).UnmarshalCompressed(data[2:])
+	if err != nil {
+		return err
+	}
+	sh.owner = binary.LittleEndian.Uint16(data[:2])
+	sh.sgn = sgn
+	return nil
+}
+
 // Marshal returns byte representation of the given signature.
 func (s *Signature) Marshal() []byte {
 	return s.sgn.Marshal()
 }
 
+// MarshalCompressed returns the compressed byte representation of the given signature.
+func (s *Signature) MarshalCompressed() []byte {
+	return s.sgn.MarshalCompressed()
+}
+
+// UnmarshalCompressed creates a signature from its byte representation created with MarshalCompressed.
+func (s *Signature) UnmarshalCompressed(data []byte) error {
+	sgn, err := new(bn256.Signature).UnmarshalCompressed(data)
+	if err != nil {
+		return err
+	}
+	s.sgn = sgn
+	return nil
+}
+
 // Unmarshal creates a signature from its byte representation.
 func (s *Signature) Unmarshal(data []byte) error {
 	if len(data) != bn256.SignatureLength {
@@ -75,27 +116,134 @@ func (tk *ThresholdKey) CombineShares(shares []*Share) (*Signature, bool) {
 		points = append(points, int64(sh.owner))
 	}
 
-	summands := make(chan *bn256.Signature)
+	sgns := make([]*bn256.Signature, len(shares))
+	coeffs := make([]*big.Int, len(shares))
 
 	var wg sync.WaitGroup
-	for _, sh := range shares {
+	for i, sh := range shares {
 		wg.Add(1)
-		go func(ch *Share) {
+		go func(i int, sh *Share) {
 			defer wg.Done()
-			summands <- bn256.MulSignature(ch.sgn, lagrange(points, int64(ch.owner)))
-		}(sh)
+			sgns[i] = sh.sgn
+			coeffs[i] = lagrange(points, int64(sh.owner))
+		}(i, sh)
 	}
-	go func() {
-		wg.Wait()
-		close(summands)
-	}()
+	wg.Wait()
 
-	var sum *bn256.Signature
-	for elem := range summands {
-		sum = bn256.AddSignatures(sum, elem)
+	sum := bn256.MultiMulSignatures(sgns, coeffs)
+	return &Signature{sgn: sum}, true
+}
+
+// CombineSharesRobust verifies the given shares of msg and combines threshold many valid ones into a Signature.
+// Shares are verified in batches, and only failing batches are split to find the invalid shares.
+// It returns the Signature, the owners of invalid shares that provided no valid share, and a bool value indicating
+// whether enough valid shares were found.
+func (tk *ThresholdKey) CombineSharesRobust(shares []*Share, msg []byte) (*Signature, []uint16, bool) {
+	var pending []*Share
+	for _, sh := range shares {
+		if sh != nil && int(sh.owner) < len(tk.vks) {
+			pending = append(pending, sh)
+		}
 	}
 
-	return &Signature{sgn: sum}, true
+	var valid []*Share
+	owners := newShareOwners()
+	for len(pending) > 0 && uint16(len(valid)) < tk.threshold {
+		need := int(tk.threshold) - len(valid)
+		var batch, rest []*Share
+		inBatch := map[uint16]bool{}
+		for _, sh := range pending {
+			if owners.valid[sh.owner] {
+				continue
+			}
+			if len(batch) < need && !inBatch[sh.owner] {
+				inBatch[sh.owner] = true
+				batch = append(batch, sh)
+			} else {
+				rest = append(rest, sh)
+			}
+		}
+		pending = rest
+		good, bad := tk.verifyShares(batch, msg)
+		for _, sh := range good {
+			owners.valid[sh.owner] = true
+			valid = append(valid, sh)
+		}
+		for _, sh := range bad {
+			owners.bad[sh.owner] = true
+		}
+	}
+
+	misbehaving := owners.misbehaving()
+	if uint16(len(valid)) < tk.threshold {
+		return nil, misbehaving, false
+	}
+	sgn, ok := tk.CombineShares(valid)
+	return sgn, misbehaving, ok
+}
+
+// shareOwners tracks the owners of verified shares while they are combined robustly.
+// At most one valid share of every owner is used, so an invalid share does not exclude a later valid one
+// of the same owner, and only owners that provided no valid share are reported as misbehaving.
+type shareOwners struct {
+	valid map[uint16]bool
+	bad   map[uint16]bool
+}
+
+func newShareOwners() *shareOwners {
+	return &shareOwners{valid: map[uint16]bool{}, bad: map[uint16]bool{}}
+}
+
+// misbehaving returns the sorted owners of invalid shares that provided no valid share.
+func (so *shareOwners) misbehaving() []uint16 {
+	var result []uint16
+	for owner := range so.bad {
+		if !so.valid[owner] {
+			result = append(result, owner)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// verifyShares splits the shares into valid and invalid ones.
+// It verifies the whole batch at once and bisects it when the verification fails.
+func (tk *ThresholdKey) verifyShares(shares []*Share, msg []byte) (valid, invalid []*Share) {
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	if tk.batchVerify(shares, msg) {
+		return append([]*Share(nil), shares...), nil
+	}
+	if len(shares) == 1 {
+		return nil, append([]*Share(nil), shares...)
+	}
+	mid := len(shares) / 2
+	valid1, invalid1 := tk.verifyShares(shares[:mid], msg)
+	valid2, invalid2 := tk.verifyShares(shares[mid:], msg)
+	return append(valid1, valid2...), append(invalid1, invalid2...)
+}
+
+// batchVerify checks whether all the shares are valid using a random linear combination,
+// which costs a single signature verification regardless of the number of shares.
+func (tk *ThresholdKey) batchVerify(shares []*Share, msg []byte) bool {
+	if len(shares) == 1 {
+		return tk.VerifyShare(shares[0], msg)
+	}
+	bound := new(big.Int).Lsh(big.NewInt(1), 128)
+	sgns := make([]*bn256.Signature, len(shares))
+	vks := make([]*bn256.VerificationKey, len(shares))
+	coeffs := make([]*big.Int, len(shares))
+	for i, sh := range shares {
+		c, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			return false
+		}
+		sgns[i] = sh.sgn
+		vks[i] = tk.vks[sh.owner]
+		coeffs[i] = c
+	}
+	return bn256.MultiMulVerificationKeys(vks, coeffs).Verify(bn256.MultiMulSignatures(sgns, coeffs), msg)
 }
 
 // CreateShare creates a Share for given process and message if the holder of
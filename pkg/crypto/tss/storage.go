@@ -0,0 +1,254 @@
+package tss
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"sort"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+const storageVersion = 1
+
+// Kinds of keys in the storage encoding.
+const (
+	storedTK byte = iota
+	storedWTK
+)
+
+var (
+	errStorageFormat   = errors.New("wrong format of stored key")
+	errStorageChecksum = errors.New("checksum of stored key does not match")
+)
+
+// MarshalStorage returns a representation of the ThresholdKey, including its decrypted secret key,
+// suitable for local storage. It should be protected, e.g. with keystore.SealThresholdKey.
+// The encoding has the following form
+// (1) version, 1 byte
+// (2) kind of the key, 1 byte
+// (3) owner, dealer, threshold and number of parties, 2 bytes as uint16 each
+// (4) globalVK, vks, encSKs and the secret key, each preceded by its length, 4 bytes as uint32
+// (5) for a WeakThresholdKey, the number of share providers followed by their pids, 2 bytes as uint16 each
+// (6) CRC-32 checksum of all the above, 4 bytes as uint32
+// Missing encSKs and the missing secret key are stored as empty.
+func (tk *ThresholdKey) MarshalStorage() []byte {
+	return appendChecksum(tk.marshalStorage(storedTK))
+}
+
+// MarshalStorage returns a representation of the WeakThresholdKey, including its decrypted secret key,
+// suitable for local storage. The encoding is described at ThresholdKey.MarshalStorage.
+func (wtk *WeakThresholdKey) MarshalStorage() []byte {
+	data := wtk.marshalStorage(storedWTK)
+	pids := make([]uint16, 0, len(wtk.shareProviders))
+	for pid, ok := range wtk.shareProviders {
+		if ok {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	data = appendUint16(data, uint16(len(pids)))
+	for _, pid := range pids {
+		data = appendUint16(data, pid)
+	}
+	return appendChecksum(data)
+}
+
+func (tk *ThresholdKey) marshalStorage(kind byte) []byte {
+	data := []byte{storageVersion, kind}
+	data = appendUint16(data, tk.owner)
+	data = appendUint16(data, tk.dealer)
+	data = appendUint16(data, tk.threshold)
+	data = appendUint16(data, uint16(len(tk.vks)))
+	data = appendBlob(data, tk.globalVK.Marshal())
+	for _, vk := range tk.vks {
+		data = appendBlob(data, vk.Marshal())
+	}
+	data = appendUint16(data, uint16(len(tk.encSKs)))
+	for _, encSK := range tk.encSKs {
+		data = appendBlob(data, encSK)
+	}
+	var sk []byte
+	if tk.sk != nil {
+		sk = tk.sk.Marshal()
+	}
+	return appendBlob(data, sk)
+}
+
+// UnmarshalThresholdKey reads a ThresholdKey from its storage representation.
+func UnmarshalThresholdKey(data []byte) (*ThresholdKey, error) {
+	r, err := newStorageReader(data, storedTK)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := r.thresholdKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(r.data) != 0 {
+		return nil, errStorageFormat
+	}
+	return tk, nil
+}
+
+// UnmarshalWeakThresholdKey reads a WeakThresholdKey from its storage representation.
+func UnmarshalWeakThresholdKey(data []byte) (*WeakThresholdKey, error) {
+	r, err := newStorageReader(data, storedWTK)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := r.thresholdKey()
+	if err != nil {
+		return nil, err
+	}
+	nProviders, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+	shareProviders := map[uint16]bool{}
+	for i := uint16(0); i < nProviders; i++ {
+		pid, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+		if pid >= tk.NProc() {
+			return nil, errStorageFormat
+		}
+		shareProviders[pid] = true
+	}
+	if len(r.data) != 0 {
+		return nil, errStorageFormat
+	}
+	return &WeakThresholdKey{ThresholdKey: *tk, shareProviders: shareProviders}, nil
+}
+
+// storageReader consumes the storage representation of a key.
+type storageReader struct {
+	data []byte
+}
+
+// newStorageReader verifies the checksum, version and kind of the stored key.
+func newStorageReader(data []byte, kind byte) (*storageReader, error) {
+	if len(data) < 2+4 {
+		return nil, errStorageFormat
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errStorageChecksum
+	}
+	if body[0] != storageVersion {
+		return nil, errors.New("unknown version of stored key")
+	}
+	if body[1] != kind {
+		return nil, errors.New("wrong kind of stored key")
+	}
+	return &storageReader{body[2:]}, nil
+}
+
+func (r *storageReader) uint16() (uint16, error) {
+	if len(r.data) < 2 {
+		return 0, errStorageFormat
+	}
+	result := binary.LittleEndian.Uint16(r.data)
+	r.data = r.data[2:]
+	return result, nil
+}
+
+func (r *storageReader) blob() ([]byte, error) {
+	if len(r.data) < 4 {
+		return nil, errStorageFormat
+	}
+	n := binary.LittleEndian.Uint32(r.data)
+	if uint32(len(r.data)-4) < n {
+		return nil, errStorageFormat
+	}
+	result := r.data[4 : 4+n]
+	r.data = r.data[4+n:]
+	return result, nil
+}
+
+func (r *storageReader) verificationKey() (*bn256.VerificationKey, error) {
+	data, err := r.blob()
+	if err != nil {
+		return nil, err
+	}
+	return new(bn256.VerificationKey).Unmarshal(data)
+}
+
+func (r *storageReader) thresholdKey() (*ThresholdKey, error) {
+	var header [4]uint16
+	for i := range header {
+		v, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+		header[i] = v
+	}
+	tk := &ThresholdKey{
+		owner:     header[0],
+		dealer:    header[1],
+		threshold: header[2],
+		vks:       make([]*bn256.VerificationKey, header[3]),
+	}
+	if tk.owner >= header[3] || tk.threshold == 0 || tk.threshold > header[3] {
+		return nil, errStorageFormat
+	}
+	var err error
+	if tk.globalVK, err = r.verificationKey(); err != nil {
+		return nil, err
+	}
+	for i := range tk.vks {
+		if tk.vks[i], err = r.verificationKey(); err != nil {
+			return nil, err
+		}
+	}
+	nEncSKs, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+	if nEncSKs != 0 && nEncSKs != header[3] {
+		return nil, errStorageFormat
+	}
+	if nEncSKs != 0 {
+		tk.encSKs = make([]encrypt.CipherText, nEncSKs)
+	}
+	for i := range tk.encSKs {
+		encSK, err := r.blob()
+		if err != nil {
+			return nil, err
+		}
+		tk.encSKs[i] = append(encrypt.CipherText{}, encSK...)
+	}
+	sk, err := r.blob()
+	if err != nil {
+		return nil, err
+	}
+	if len(sk) > 0 {
+		if tk.sk, err = new(bn256.SecretKey).Unmarshal(sk); err != nil {
+			return nil, err
+		}
+		if !bn256.VerifyKeys(tk.vks[tk.owner], tk.sk) {
+			return nil, errors.New("stored secret key does not match the verification key")
+		}
+	}
+	return tk, nil
+}
+
+func appendUint16(data []byte, v uint16) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, v)
+	return append(data, buf...)
+}
+
+func appendBlob(data, blob []byte) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(len(blob)))
+	return append(append(data, buf...), blob...)
+}
+
+func appendChecksum(data []byte) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, crc32.ChecksumIEEE(data))
+	return append(data, buf...)
+}
@@ -0,0 +1,93 @@
+package tss_test
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage encoding", func() {
+	var (
+		n, t, dealer, owner uint16
+		tk                  *ThresholdKey
+		wtk                 *WeakThresholdKey
+		msg                 []byte
+	)
+	BeforeEach(func() {
+		n, t, dealer, owner = 4, 2, 1, 3
+		msg = []byte("xyz")
+		sKeys := make([]*p2p.SecretKey, n)
+		pKeys := make([]*p2p.PublicKey, n)
+		for i := uint16(0); i < n; i++ {
+			pKeys[i], sKeys[i], _ = p2p.GenerateKeys()
+		}
+		dealerKeys, err := p2p.Keys(sKeys[dealer], pKeys, dealer)
+		Expect(err).NotTo(HaveOccurred())
+		dealt, err := NewRandom(n, t).Encrypt(dealer, dealerKeys)
+		Expect(err).NotTo(HaveOccurred())
+		ownerKeys, err := p2p.Keys(sKeys[owner], pKeys, owner)
+		Expect(err).NotTo(HaveOccurred())
+		var ok bool
+		tk, ok, err = Decode(dealt.Encode(), dealer, owner, ownerKeys[dealer])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		wtk = CreateWTK([]*ThresholdKey{tk}, map[uint16]bool{0: true, owner: true})
+	})
+	It("should restore a ThresholdKey", func() {
+		tk2, err := UnmarshalThresholdKey(tk.MarshalStorage())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tk2.Encode()).To(Equal(tk.Encode()))
+		Expect(tk2.Threshold()).To(Equal(t))
+		Expect(tk2.CreateShare(msg).Marshal()).To(Equal(tk.CreateShare(msg).Marshal()))
+		Expect(tk2.MarshalStorage()).To(Equal(tk.MarshalStorage()))
+	})
+	It("should restore a WeakThresholdKey", func() {
+		wtk2, err := UnmarshalWeakThresholdKey(wtk.MarshalStorage())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wtk2.ShareProviders()).To(Equal(wtk.ShareProviders()))
+		Expect(wtk2.GlobalVK().Marshal()).To(Equal(wtk.GlobalVK().Marshal()))
+		Expect(wtk2.CreateShare(msg).Marshal()).To(Equal(wtk.CreateShare(msg).Marshal()))
+		Expect(wtk2.MarshalStorage()).To(Equal(wtk.MarshalStorage()))
+	})
+	It("should restore a key without a secret share", func() {
+		public := NewPublic(t, tk.GlobalVK(), tk.VerificationKeys())
+		public2, err := UnmarshalThresholdKey(public.MarshalStorage())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(public2.VerificationKeys()).To(HaveLen(int(n)))
+		Expect(public2.VerifyShare(tk.CreateShare(msg), msg)).To(BeTrue())
+	})
+	It("should detect corrupted data", func() {
+		data := tk.MarshalStorage()
+		data[len(data)/2] ^= 1
+		_, err := UnmarshalThresholdKey(data)
+		Expect(err).To(HaveOccurred())
+		_, err = UnmarshalThresholdKey(data[:len(data)-1])
+		Expect(err).To(HaveOccurred())
+	})
+	It("should not confuse the kinds of keys", func() {
+		_, err := UnmarshalWeakThresholdKey(tk.MarshalStorage())
+		Expect(err).To(HaveOccurred())
+		_, err = UnmarshalThresholdKey(wtk.MarshalStorage())
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject a secret share not matching the verification key", func() {
+		_, sk, err := bn256.GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		data := NewPublic(t, tk.GlobalVK(), tk.VerificationKeys()).MarshalStorage()
+		// replace the empty secret key at the end with a random one and fix the checksum
+		body := append([]byte{}, data[:len(data)-8]...)
+		lenBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lenBuf, uint32(len(sk.Marshal())))
+		body = append(append(body, lenBuf...), sk.Marshal()...)
+		sumBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sumBuf, crc32.ChecksumIEEE(body))
+		_, err = UnmarshalThresholdKey(append(body, sumBuf...))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -54,6 +54,11 @@ func (tk *ThresholdKey) Threshold() uint16 {
 	return tk.threshold
 }
 
+// NProc returns the number of parties holding shares of the key.
+func (tk *ThresholdKey) NProc() uint16 {
+	return uint16(len(tk.vks))
+}
+
 // ShareProviders returns the map describing which parties may produce shares of signatures.
 func (wkt *WeakThresholdKey) ShareProviders() map[uint16]bool {
 	return wkt.shareProviders
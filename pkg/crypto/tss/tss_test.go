@@ -1,6 +1,10 @@
 package tss_test
 
 import (
+	"fmt"
+	"testing"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto"
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
 	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
 	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
@@ -36,7 +40,7 @@ var _ = Describe("T", func() {
 				for i := uint16(0); i < n; i++ {
 					p2pKeys[i], _ = p2p.Keys(sKeys[i], pKeys, i)
 				}
-				tc, err := gtc.Encrypt(p2pKeys[dealer])
+				tc, err := gtc.Encrypt(dealer, p2pKeys[dealer])
 				Expect(err).NotTo(HaveOccurred())
 				tcEncoded := tc.Encode()
 				for i := uint16(0); i < n; i++ {
@@ -49,6 +53,41 @@ var _ = Describe("T", func() {
 					shares[i] = tcs[i].CreateShare(msg)
 				}
 			})
+			It("Should not decode a secret key attributed to another dealer", func() {
+				_, ok, err := Decode(tcs[0].Encode(), dealer+1, 0, p2pKeys[0][dealer])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+			It("Should be robustly combined skipping invalid shares", func() {
+				bad := tcs[1].CreateShare(append(msg, byte(1)))
+				mixed := []*Share{shares[0], bad, shares[1], shares[1], shares[2], shares[3]}
+				c, misbehaving, ok := tcs[0].CombineSharesRobust(mixed, msg)
+				Expect(ok).To(BeTrue())
+				Expect(misbehaving).To(BeEmpty())
+				Expect(tcs[0].VerifySignature(c, msg)).To(BeTrue())
+			})
+			It("Should not let an invalid share exclude a later valid share of the same owner", func() {
+				spoofed := tcs[5].CreateShare(append(msg, byte(1)))
+				fake := new(Share)
+				Expect(fake.Unmarshal(append([]byte{0, 0}, spoofed.Marshal()[2:]...))).To(Succeed())
+				c, misbehaving, ok := tcs[0].CombineSharesRobust([]*Share{fake, nil, shares[0], shares[1], shares[2]}, msg)
+				Expect(ok).To(BeTrue())
+				Expect(misbehaving).To(BeEmpty())
+				Expect(tcs[0].VerifySignature(c, msg)).To(BeTrue())
+			})
+			It("Should report all the invalid shares when robustly combining", func() {
+				mixed := []*Share{tcs[4].CreateShare([]byte("a")), shares[5], tcs[6].CreateShare([]byte("b")), shares[7], shares[8]}
+				c, misbehaving, ok := tcs[0].CombineSharesRobust(mixed, msg)
+				Expect(ok).To(BeTrue())
+				Expect(misbehaving).To(ConsistOf(uint16(4), uint16(6)))
+				Expect(tcs[0].VerifySignature(c, msg)).To(BeTrue())
+			})
+			It("Shouldn't be robustly combined without enough valid shares", func() {
+				bad := tcs[2].CreateShare(append(msg, byte(1)))
+				_, misbehaving, ok := tcs[0].CombineSharesRobust([]*Share{shares[0], shares[1], bad}, msg)
+				Expect(ok).To(BeFalse())
+				Expect(misbehaving).To(Equal([]uint16{2}))
+			})
 			It("should be verified correctly", func() {
 				Expect(tcs[2].VerifyShare(shares[1], msg)).To(BeTrue())
 				Expect(tcs[2].VerifyShare(shares[1], append(msg, byte(1)))).To(BeFalse())
@@ -76,6 +115,20 @@ var _ = Describe("T", func() {
 				_, ok := tcs[0].CombineShares(shares[:(t - 1)])
 				Expect(ok).To(BeFalse())
 			})
+			It("Should be marshalled compressed and unmarshalled correctly", func() {
+				for i := uint16(0); i < n; i++ {
+					var cs = new(Share)
+					Expect(cs.UnmarshalCompressed(shares[i].MarshalCompressed())).To(Succeed())
+					Expect(new(Share).Unmarshal(shares[i].MarshalCompressed())).NotTo(Succeed())
+					Expect(tcs[0].VerifyShare(cs, msg)).To(BeTrue())
+				}
+				c, ok := tcs[0].CombineShares(shares[:t])
+				Expect(ok).To(BeTrue())
+				c2 := new(Signature)
+				Expect(c2.UnmarshalCompressed(c.MarshalCompressed())).To(Succeed())
+				Expect(new(Signature).Unmarshal(c.MarshalCompressed())).NotTo(Succeed())
+				Expect(tcs[0].VerifySignature(c2, msg)).To(BeTrue())
+			})
 			It("Should be marshalled and unmarshalled correctly", func() {
 				for i := uint16(0); i < n; i++ {
 					csMarshalled := shares[i].Marshal()
@@ -87,6 +140,36 @@ var _ = Describe("T", func() {
 			})
 		})
 	})
+	Context("Dealing encrypted with public keys", func() {
+		It("Should be decoded by the recipients with their secret keys", func() {
+			n, t = 4, 2
+			sKeys = make([]*p2p.SecretKey, n)
+			pKeys = make([]*p2p.PublicKey, n)
+			for i := uint16(0); i < n; i++ {
+				pKeys[i], sKeys[i], _ = p2p.GenerateKeys()
+			}
+			tc, err := NewRandom(n, t).EncryptPublic(0, pKeys)
+			Expect(err).NotTo(HaveOccurred())
+			tcEncoded := tc.Encode()
+			msg = []byte("xyz")
+			shares = make([]*Share, n)
+			for i := uint16(0); i < n; i++ {
+				tk, ok, err := Decode(tcEncoded, 0, i, sKeys[i])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				shares[i] = tk.CreateShare(msg)
+			}
+			_, ok, err := Decode(tcEncoded, 0, 1, sKeys[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			_, ok, err = Decode(tcEncoded, 1, 0, sKeys[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			c, ok := tc.CombineShares(shares[:t])
+			Expect(ok).To(BeTrue())
+			Expect(tc.VerifySignature(c, msg)).To(BeTrue())
+		})
+	})
 	Context("Signature unmarshal", func() {
 		Context("On an empty slice", func() {
 			It("Should return an error", func() {
@@ -146,9 +229,9 @@ var _ = Describe("T", func() {
 			}
 
 			gtc1 := NewRandom(n, t)
-			tc1, _ := gtc1.Encrypt(p2pKeys[0])
+			tc1, _ := gtc1.Encrypt(0, p2pKeys[0])
 			gtc2 := NewRandom(n, t)
-			tc2, _ := gtc2.Encrypt(p2pKeys[1])
+			tc2, _ := gtc2.Encrypt(1, p2pKeys[1])
 
 			tc1Encoded := tc1.Encode()
 			tc2Encoded := tc2.Encode()
@@ -183,3 +266,26 @@ var _ = Describe("T", func() {
 		})
 	})
 })
+
+func BenchmarkCombineShares(b *testing.B) {
+	for _, n := range []uint16{4, 10, 100, 1000} {
+		t := crypto.MinimalTrusted(n)
+		keys := make([]encrypt.SymmetricKey, n)
+		for i := range keys {
+			keys[i], _ = encrypt.NewSymmetricKey([]byte{byte(i), byte(i >> 8)})
+		}
+		tk, _ := NewRandom(n, t).Encrypt(0, keys)
+		_, sk, _ := bn256.GenerateKeys()
+		shares := make([]*Share, t)
+		for i := uint16(0); i < t; i++ {
+			data := []byte{byte(i), byte(i >> 8)}
+			shares[i] = new(Share)
+			shares[i].Unmarshal(append(data, sk.Sign(data).Marshal()...))
+		}
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tk.CombineShares(shares)
+			}
+		})
+	}
+}
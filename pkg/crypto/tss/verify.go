@@ -2,6 +2,7 @@ package tss
 
 import (
 	"crypto/subtle"
+	"math/big"
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
 )
@@ -22,6 +23,23 @@ func (tk *ThresholdKey) PolyVerify(pv bn256.PolyVerifier) bool {
 	return pv.Verify(tk.vks)
 }
 
+// VerifyPublicKeys checks whether the verification keys, together with the global verification key,
+// are the values of a single polynomial of degree at most threshold-1.
+func (tk *ThresholdKey) VerifyPublicKeys() bool {
+	if tk.threshold == 0 || int(tk.threshold) > len(tk.vks) {
+		return false
+	}
+	points := make([]*big.Int, len(tk.vks)+1)
+	for i := range points {
+		points[i] = big.NewInt(int64(i))
+	}
+	pv, err := bn256.NewPolyVerifierAt(points, int(tk.threshold)-1)
+	if err != nil {
+		return false
+	}
+	return pv.Verify(append([]*bn256.VerificationKey{tk.globalVK}, tk.vks...))
+}
+
 // VerifySecretKey checks if the verificationKey and secretKey form a valid pair.
 // It returns the incorrect secret key when the pair of keys is invalid or
 // nil when the keys are valid.
@@ -0,0 +1,237 @@
+package tss
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+)
+
+// WeightedThresholdKey is a threshold key of a committee with weighted members.
+// A member of weight w holds w consecutive shares of an underlying threshold key,
+// so the threshold is expressed in terms of the total weight of members.
+type WeightedThresholdKey struct {
+	tk      ThresholdKey
+	owner   uint16
+	weights []uint16
+	offsets []uint16
+	sks     []*bn256.SecretKey
+}
+
+// WeightedShare is a share of a signature created by a weighted member, consisting of a signature for each of its shares.
+type WeightedShare struct {
+	owner uint16
+	sgns  []*bn256.Signature
+}
+
+// Owner returns owner's PID of this share.
+func (sh *WeightedShare) Owner() uint16 {
+	return sh.owner
+}
+
+// weightOffsets returns the index of the first share of every member and the total weight.
+func weightOffsets(weights []uint16) ([]uint16, uint64) {
+	offsets := make([]uint16, len(weights))
+	sum := uint64(0)
+	for i, w := range weights {
+		offsets[i] = uint16(sum)
+		sum += uint64(w)
+	}
+	return offsets, sum
+}
+
+// EncryptWeighted encrypts the secret keys of the given TSS dealt by the dealer for members with the given weights,
+// using their encryptionKeys, and returns an (unowned) ThresholdKey.
+// The TSS should be dealt for the total weight of members.
+func (tss *TSS) EncryptWeighted(dealer uint16, weights []uint16, encryptionKeys []encrypt.SymmetricKey) (*ThresholdKey, error) {
+	if len(weights) != len(encryptionKeys) {
+		return nil, errors.New("numbers of weights and keys differ")
+	}
+	keys := make([]encrypt.SymmetricKey, 0, len(tss.sks))
+	for i, w := range weights {
+		for j := uint16(0); j < w; j++ {
+			keys = append(keys, encryptionKeys[i])
+		}
+	}
+	if len(keys) != len(tss.sks) {
+		return nil, errors.New("total weight differs from the number of shares")
+	}
+	return tss.Encrypt(dealer, keys)
+}
+
+// DecodeWeighted decodes the encoded ThresholdKey obtained from the dealer of a weighted TSS using given decryptionKey.
+// It returns
+// (1) decoded WeightedThresholdKey,
+// (2) whether all the secret keys of the owner are correctly encoded and match corresponding verification keys,
+// (3) an error in decoding (excluding errors obtained while decoding owner's secret keys).
+func DecodeWeighted(data []byte, dealer, owner uint16, weights []uint16, decryptionKey Decrypter) (*WeightedThresholdKey, bool, error) {
+	if int(owner) >= len(weights) {
+		return nil, false, errors.New("owner out of range")
+	}
+	tk, err := decodeDealing(data, dealer)
+	if err != nil {
+		return nil, false, err
+	}
+	offsets, total := weightOffsets(weights)
+	if uint64(len(tk.vks)) != total {
+		return nil, false, errors.New("total weight differs from the number of shares")
+	}
+	tk.owner = owner
+	sks := make([]*bn256.SecretKey, weights[owner])
+	ok := true
+	for i := range sks {
+		ind := offsets[owner] + uint16(i)
+		sks[i], err = tk.decryptSecretKey(ind, decryptionKey)
+		if err != nil {
+			ok = false
+		}
+	}
+	return &WeightedThresholdKey{
+		tk:      *tk,
+		owner:   owner,
+		weights: weights,
+		offsets: offsets,
+		sks:     sks,
+	}, ok, nil
+}
+
+// Threshold returns the total weight of members needed to create a signature.
+func (wk *WeightedThresholdKey) Threshold() uint16 {
+	return wk.tk.threshold
+}
+
+// Weight returns the weight of the given member.
+func (wk *WeightedThresholdKey) Weight(pid uint16) uint16 {
+	return wk.weights[pid]
+}
+
+// GlobalVK returns the global verification key of the threshold key.
+func (wk *WeightedThresholdKey) GlobalVK() *bn256.VerificationKey {
+	return wk.tk.globalVK
+}
+
+// CreateShare creates a WeightedShare for the given message.
+// It returns nil if some secret key of the owner is missing.
+func (wk *WeightedThresholdKey) CreateShare(msg []byte) *WeightedShare {
+	sgns := make([]*bn256.Signature, len(wk.sks))
+	for i, sk := range wk.sks {
+		if sk == nil {
+			return nil
+		}
+		sgns[i] = sk.Sign(msg)
+	}
+	return &WeightedShare{owner: wk.owner, sgns: sgns}
+}
+
+// shares returns the shares of the underlying threshold key contained in the weighted share.
+func (wk *WeightedThresholdKey) shares(sh *WeightedShare) []*Share {
+	result := make([]*Share, len(sh.sgns))
+	for i, sgn := range sh.sgns {
+		result[i] = &Share{owner: wk.offsets[sh.owner] + uint16(i), sgn: sgn}
+	}
+	return result
+}
+
+// VerifyShare verifies whether the given weighted share is correct.
+func (wk *WeightedThresholdKey) VerifyShare(sh *WeightedShare, msg []byte) bool {
+	if int(sh.owner) >= len(wk.weights) || len(sh.sgns) != int(wk.weights[sh.owner]) {
+		return false
+	}
+	if len(sh.sgns) == 0 {
+		return true
+	}
+	return wk.tk.batchVerify(wk.shares(sh), msg)
+}
+
+// VerifySignature verifies whether the given signature is correct.
+func (wk *WeightedThresholdKey) VerifySignature(s *Signature, msg []byte) bool {
+	return wk.tk.VerifySignature(s, msg)
+}
+
+// CombineShares combines the given weighted shares into a Signature.
+// It succeeds once the total weight of the owners of the shares reaches the threshold.
+// Only the first share of every owner is used, and the shares should be verified beforehand.
+func (wk *WeightedThresholdKey) CombineShares(shares []*WeightedShare) (*Signature, bool) {
+	var result []*Share
+	seen := map[uint16]bool{}
+	for _, sh := range shares {
+		if len(result) >= int(wk.tk.threshold) {
+			break
+		}
+		if sh == nil || int(sh.owner) >= len(wk.weights) || seen[sh.owner] || len(sh.sgns) != int(wk.weights[sh.owner]) {
+			continue
+		}
+		seen[sh.owner] = true
+		result = append(result, wk.shares(sh)...)
+	}
+	return wk.tk.CombineShares(result)
+}
+
+// CombineSharesRobust verifies the given weighted shares of msg and combines the valid ones into a Signature,
+// once their total weight reaches the threshold.
+// It returns the Signature, the owners of invalid shares that provided no valid share, and a bool value
+// indicating whether the valid shares had enough weight.
+func (wk *WeightedThresholdKey) CombineSharesRobust(shares []*WeightedShare, msg []byte) (*Signature, []uint16, bool) {
+	var valid []*WeightedShare
+	owners := newShareOwners()
+	weight := uint64(0)
+	for _, sh := range shares {
+		if weight >= uint64(wk.tk.threshold) {
+			break
+		}
+		if sh == nil || int(sh.owner) >= len(wk.weights) || owners.valid[sh.owner] {
+			continue
+		}
+		if !wk.VerifyShare(sh, msg) {
+			owners.bad[sh.owner] = true
+			continue
+		}
+		owners.valid[sh.owner] = true
+		valid = append(valid, sh)
+		weight += uint64(wk.weights[sh.owner])
+	}
+	misbehaving := owners.misbehaving()
+	if weight < uint64(wk.tk.threshold) {
+		return nil, misbehaving, false
+	}
+	sgn, ok := wk.CombineShares(valid)
+	return sgn, misbehaving, ok
+}
+
+// Marshal returns byte representation of the given weighted share in the following form
+// (1) owner, 2 bytes as uint16
+// (2) number of signatures, 2 bytes as uint16
+// (3) signatures
+func (sh *WeightedShare) Marshal() []byte {
+	data := make([]byte, 4, 4+len(sh.sgns)*bn256.SignatureLength)
+	binary.LittleEndian.PutUint16(data[:2], sh.owner)
+	binary.LittleEndian.PutUint16(data[2:4], uint16(len(sh.sgns)))
+	for _, sgn := range sh.sgns {
+		data = append(data, sgn.Marshal()...)
+	}
+	return data
+}
+
+// Unmarshal reads a weighted share from its byte representation.
+func (sh *WeightedShare) Unmarshal(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("given data is too short")
+	}
+	count := int(binary.LittleEndian.Uint16(data[2:4]))
+	if len(data) != 4+count*bn256.SignatureLength {
+		return errors.New("wrong length of weighted share")
+	}
+	sgns := make([]*bn256.Signature, count)
+	for i := range sgns {
+		start := 4 + i*bn256.SignatureLength
+		sgn, err := new(bn256.Signature).Unmarshal(data[start : start+bn256.SignatureLength])
+		if err != nil {
+			return err
+		}
+		sgns[i] = sgn
+	}
+	sh.owner = binary.LittleEndian.Uint16(data[:2])
+	sh.sgns = sgns
+	return nil
+}
@@ -0,0 +1,103 @@
+package tss_test
+
+import (
+	"gitlab.com/alephledger/core-go/pkg/crypto"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	. "gitlab.com/alephledger/core-go/pkg/crypto/tss"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Weighted", func() {
+	var (
+		n, dealer uint16
+		weights   []uint16
+		wks       []*WeightedThresholdKey
+		shares    []*WeightedShare
+		msg       []byte
+	)
+	BeforeEach(func() {
+		n, dealer = 4, 2
+		weights = []uint16{1, 3, 2, 1}
+		msg = []byte("xyz")
+		sKeys := make([]*p2p.SecretKey, n)
+		pKeys := make([]*p2p.PublicKey, n)
+		for i := uint16(0); i < n; i++ {
+			pKeys[i], sKeys[i], _ = p2p.GenerateKeys()
+		}
+		p2pKeys := make([][]encrypt.SymmetricKey, n)
+		for i := uint16(0); i < n; i++ {
+			p2pKeys[i], _ = p2p.Keys(sKeys[i], pKeys, i)
+		}
+		threshold := crypto.MinimalWeightedTrusted(weights)
+		Expect(threshold).To(Equal(uint64(3)))
+		dealt, err := NewRandom(uint16(crypto.TotalWeight(weights)), uint16(threshold)).EncryptWeighted(dealer, weights, p2pKeys[dealer])
+		Expect(err).NotTo(HaveOccurred())
+		encoded := dealt.Encode()
+		wks = make([]*WeightedThresholdKey, n)
+		shares = make([]*WeightedShare, n)
+		for i := uint16(0); i < n; i++ {
+			var ok bool
+			wks[i], ok, err = DecodeWeighted(encoded, dealer, i, weights, p2pKeys[i][dealer])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			shares[i] = wks[i].CreateShare(msg)
+		}
+	})
+	It("should verify shares", func() {
+		for i := uint16(0); i < n; i++ {
+			Expect(wks[0].VerifyShare(shares[i], msg)).To(BeTrue())
+			Expect(wks[0].VerifyShare(shares[i], append(msg, 1))).To(BeFalse())
+		}
+	})
+	It("should combine a share of a member with enough weight", func() {
+		sgn, ok := wks[0].CombineShares([]*WeightedShare{shares[1]})
+		Expect(ok).To(BeTrue())
+		Expect(wks[0].VerifySignature(sgn, msg)).To(BeTrue())
+	})
+	It("should combine to the same signature for different sets of members", func() {
+		sgn1, ok := wks[0].CombineShares([]*WeightedShare{shares[0], shares[2]})
+		Expect(ok).To(BeTrue())
+		sgn2, ok := wks[3].CombineShares([]*WeightedShare{shares[3], shares[0], shares[1]})
+		Expect(ok).To(BeTrue())
+		Expect(sgn1.Marshal()).To(Equal(sgn2.Marshal()))
+	})
+	It("should not combine shares with too little weight", func() {
+		_, ok := wks[0].CombineShares([]*WeightedShare{shares[0], shares[3], shares[0]})
+		Expect(ok).To(BeFalse())
+	})
+	It("should skip invalid shares when combining robustly", func() {
+		bad := wks[2].CreateShare(append(msg, 1))
+		sgn, misbehaving, ok := wks[0].CombineSharesRobust([]*WeightedShare{bad, shares[0], shares[3], shares[1]}, msg)
+		Expect(ok).To(BeTrue())
+		Expect(misbehaving).To(Equal([]uint16{2}))
+		Expect(wks[0].VerifySignature(sgn, msg)).To(BeTrue())
+		_, _, ok = wks[0].CombineSharesRobust([]*WeightedShare{bad, shares[0], shares[3]}, msg)
+		Expect(ok).To(BeFalse())
+	})
+	It("should not let an invalid share exclude a later valid share of the same owner", func() {
+		bad := wks[1].CreateShare(append(msg, 1))
+		sgn, misbehaving, ok := wks[0].CombineSharesRobust([]*WeightedShare{bad, nil, shares[1]}, msg)
+		Expect(ok).To(BeTrue())
+		Expect(misbehaving).To(BeEmpty())
+		Expect(wks[0].VerifySignature(sgn, msg)).To(BeTrue())
+	})
+	It("should marshal and unmarshal shares", func() {
+		sh := new(WeightedShare)
+		Expect(sh.Unmarshal(shares[1].Marshal())).To(Succeed())
+		Expect(sh.Owner()).To(Equal(uint16(1)))
+		Expect(wks[0].VerifyShare(sh, msg)).To(BeTrue())
+	})
+	It("should compute weights of subsets", func() {
+		Expect(crypto.WeightOf(weights, []uint16{1, 3, 1})).To(Equal(uint64(4)))
+		Expect(crypto.MinimalWeightedQuorum(weights)).To(Equal(uint64(5)))
+	})
+	It("should not wrap around when summing large weights", func() {
+		large := []uint16{65535, 65535, 2}
+		Expect(crypto.TotalWeight(large)).To(Equal(uint64(131072)))
+		Expect(crypto.WeightOf(large, []uint16{0, 1})).To(Equal(uint64(131070)))
+		Expect(crypto.MinimalWeightedTrusted(large)).To(Equal(uint64(43691)))
+	})
+})
@@ -0,0 +1,467 @@
+// Package dkg implements a distributed key generation protocol producing a tss.WeakThresholdKey.
+//
+// Every member deals a random threshold key and multicasts it using rmcbox.
+// The members agree on the set of dealings that were multicast successfully,
+// after which every member checks its shares in these dealings and multicasts complaints about the incorrect ones.
+// Then the members agree on the reports of complaints that are taken into account.
+// Dealers proven faulty by the complaints are excluded, and so are members who complained falsely or did not report on time.
+// The remaining dealings are summed up into a WeakThresholdKey, with the members who reported as share providers.
+//
+// Every agreement proceeds in views, each with a different coordinator proposing the set. A member that holds
+// the proof of the proposal of its current view locks on it and multicasts a commit. A set is decided once a quorum
+// of members committed to it. A member that does not see a decision on time abandons the view, reporting its lock,
+// and the coordinator of the next view has to propose the set of the latest lock among a quorum of such reports.
+// Hence a faulty coordinator cannot make honest members decide differently, it can only delay the agreement.
+//
+// The protocol proceeds in phases bounded by the timeout from the config.
+package dkg
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto"
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	"gitlab.com/alephledger/core-go/pkg/rmcbox"
+)
+
+// Config contains the keys of a committee member and the parameters of the protocol.
+type Config struct {
+	// Pid of this member.
+	Pid uint16
+	// PublicKeys are the keys used to verify signatures of all the members.
+	PublicKeys []*bn256.VerificationKey
+	// PrivateKey is the signing key of this member.
+	PrivateKey *bn256.SecretKey
+	// P2PPublicKeys are the keys used to derive pairwise symmetric keys of all the members.
+	P2PPublicKeys []*p2p.PublicKey
+	// P2PSecretKey is the p2p secret key of this member.
+	P2PSecretKey *p2p.SecretKey
+	// Timeout bounds the duration of every phase of the protocol.
+	Timeout time.Duration
+}
+
+// DKG is a single execution of the distributed key generation protocol.
+type DKG struct {
+	conf      Config
+	pid       uint16
+	nProc     uint16
+	threshold uint16
+	netserv   network.Server
+	rmc       *rmcbox.RMC
+	p2pKeys   []encrypt.SymmetricKey
+	stages    map[byte]*stage
+	quit      chan struct{}
+	// wg counts the goroutines sending messages, which Run waits for before returning.
+	wg sync.WaitGroup
+	// dealing, complaints and accusers are replaced only in tests, to simulate byzantine members.
+	dealing    func(keys []encrypt.SymmetricKey) (*tss.ThresholdKey, error)
+	complaints func(dealers []uint16) []*tss.Complaint
+	accusers   func(chosen []uint16) []uint16
+}
+
+// stage describes an agreement on the members whose multicasts of the subject kind are taken into account.
+type stage struct {
+	subject, proposal, commit, abandon byte
+	// min is the minimal number of chosen members.
+	min int
+}
+
+// New creates a DKG for the member described by the config, communicating through the given network server.
+func New(conf Config, netserv network.Server) (*DKG, error) {
+	nProc := uint16(len(conf.PublicKeys))
+	if int(nProc) != len(conf.P2PPublicKeys) {
+		return nil, errors.New("numbers of public keys and p2p public keys differ")
+	}
+	if conf.Pid >= nProc {
+		return nil, errors.New("pid out of range")
+	}
+	p2pKeys, err := p2p.Keys(conf.P2PSecretKey, conf.P2PPublicKeys, conf.Pid)
+	if err != nil {
+		return nil, err
+	}
+	rmc, err := rmcbox.New(conf.PublicKeys, conf.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	d := &DKG{
+		conf:      conf,
+		pid:       conf.Pid,
+		nProc:     nProc,
+		threshold: crypto.MinimalTrusted(nProc),
+		netserv:   netserv,
+		rmc:       rmc,
+		p2pKeys:   p2pKeys,
+		quit:      make(chan struct{}),
+	}
+	d.dealing = func(keys []encrypt.SymmetricKey) (*tss.ThresholdKey, error) {
+		return tss.NewRandom(d.nProc, d.threshold).Encrypt(d.pid, keys)
+	}
+	d.complaints = d.findComplaints
+	d.accusers = func(chosen []uint16) []uint16 { return chosen }
+	// Only the chosen accusers can become share providers, so the complaints of at least a quorum of members are needed
+	// for enough of them to be honest.
+	quorum := int(crypto.MinimalQuorum(nProc))
+	dealings := &stage{dealingKind, dealingProposalKind, dealingCommitKind, dealingAbandonKind, int(d.threshold)}
+	complaints := &stage{complaintKind, complaintProposalKind, complaintCommitKind, complaintAbandonKind, quorum}
+	d.stages = map[byte]*stage{}
+	for _, st := range []*stage{dealings, complaints} {
+		for _, kind := range []byte{st.proposal, st.commit, st.abandon} {
+			d.stages[kind] = st
+		}
+	}
+	return d, nil
+}
+
+// Run executes the protocol and returns the resulting WeakThresholdKey.
+// It should be called only once.
+func (d *DKG) Run() (*tss.WeakThresholdKey, error) {
+	go d.listen()
+	defer func() {
+		close(d.quit)
+		d.wg.Wait()
+	}()
+
+	tk, err := d.dealing(d.p2pKeys)
+	if err != nil {
+		return nil, err
+	}
+	d.multicast(newID(dealingKind, 0, d.pid), tk.Encode())
+
+	dealers, err := d.agree(d.stages[dealingProposalKind])
+	if err != nil {
+		return nil, err
+	}
+
+	d.multicast(newID(complaintKind, 0, d.pid), encodeComplaints(d.complaints(dealers)))
+
+	accusers, err := d.agree(d.stages[complaintProposalKind])
+	if err != nil {
+		return nil, err
+	}
+
+	return d.result(dealers, accusers)
+}
+
+// coordinatorOf returns the pid of the member proposing in the given view.
+func (d *DKG) coordinatorOf(view uint16) uint16 {
+	return view % d.nProc
+}
+
+// lock is the proposal of the latest view whose proof a member held while in that view.
+type lock struct {
+	view   uint16
+	chosen []uint16
+}
+
+// agree returns the list of members whose multicasts of the subject kind were taken into account,
+// the same for all honest members. It tries consecutive views until a proposal is decided,
+// and waits until all the multicasts it points to finish.
+func (d *DKG) agree(st *stage) ([]uint16, error) {
+	var locked *lock
+	for view := uint16(0); view < d.nProc; view++ {
+		if d.pid == d.coordinatorOf(view) {
+			d.wg.Add(1)
+			go func(view uint16) {
+				defer d.wg.Done()
+				d.propose(st, view)
+			}(view)
+		}
+		proposalID := newID(st.proposal, view, d.coordinatorOf(view))
+		// The coordinator may wait a whole timeout for the subjects, then the proposal and the commits follow.
+		deadline := time.After(3 * d.conf.Timeout)
+		ticker := time.NewTicker(pollInterval)
+	loop:
+		for {
+			if chosen, ok := d.decided(st, view); ok {
+				ticker.Stop()
+				return d.awaitChosen(st, chosen)
+			}
+			if (locked == nil || locked.view < view) && d.rmc.Status(proposalID) == rmcbox.Finished {
+				chosen, _, err := decodeProposal(d.rmc.Data(proposalID), d.nProc)
+				if err == nil {
+					locked = &lock{view, chosen}
+					d.forward(proposalID)
+					d.multicast(newID(st.commit, view, d.pid), nil)
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-deadline:
+				break loop
+			}
+		}
+		ticker.Stop()
+		d.multicast(newID(st.abandon, view, d.pid), encodeLock(locked))
+	}
+	// Commits of the last view may still be finishing.
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	deadline := time.After(d.conf.Timeout)
+	for {
+		if chosen, ok := d.decided(st, d.nProc-1); ok {
+			return d.awaitChosen(st, chosen)
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return nil, errors.New("no agreement reached")
+		}
+	}
+}
+
+// decided returns the proposal of a view not later than the given one, to which a quorum of members committed.
+func (d *DKG) decided(st *stage, last uint16) ([]uint16, bool) {
+	quorum := int(crypto.MinimalQuorum(d.nProc))
+	for view := uint16(0); view <= last; view++ {
+		proposalID := newID(st.proposal, view, d.coordinatorOf(view))
+		if d.rmc.Status(proposalID) != rmcbox.Finished {
+			continue
+		}
+		if len(d.finished(st.commit, view)) < quorum {
+			continue
+		}
+		chosen, _, err := decodeProposal(d.rmc.Data(proposalID), d.nProc)
+		if err != nil {
+			continue
+		}
+		return chosen, true
+	}
+	return nil, false
+}
+
+// awaitChosen waits until the multicasts of the chosen members finish.
+func (d *DKG) awaitChosen(st *stage, chosen []uint16) ([]uint16, error) {
+	if !d.await(d.conf.Timeout, d.subjectIDs(st, chosen)...) {
+		return nil, errors.New("chosen multicasts did not finish on time")
+	}
+	return chosen, nil
+}
+
+// propose multicasts the proposal of this member as the coordinator of the given view.
+// In the first view it chooses the members whose multicasts finished. In later views it waits for a quorum
+// of abandons of the previous view, and repeats the latest lock among them, if any.
+func (d *DKG) propose(st *stage, view uint16) {
+	var chosen, justification []uint16
+	if view == 0 {
+		chosen = d.awaitAll(st.subject)
+	} else {
+		quorum := int(crypto.MinimalQuorum(d.nProc))
+		deadline := time.After(d.conf.Timeout)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for len(justification) < quorum {
+			select {
+			case <-ticker.C:
+			case <-deadline:
+				return
+			}
+			justification = d.finished(st.abandon, view-1)
+		}
+		latest, err := d.latestLock(st, view, justification)
+		if err != nil {
+			return
+		}
+		if latest != nil {
+			chosen = latest.chosen
+		} else {
+			chosen = d.finished(st.subject, 0)
+		}
+		for _, pid := range justification {
+			d.forward(newID(st.abandon, view-1, pid))
+		}
+	}
+	if len(chosen) < st.min {
+		return
+	}
+	if st.subject == complaintKind {
+		chosen = d.accusers(chosen)
+	}
+	for _, id := range d.subjectIDs(st, chosen) {
+		d.forward(id)
+	}
+	d.multicast(newID(st.proposal, view, d.pid), encodeProposal(chosen, justification))
+}
+
+// latestLock returns the latest lock reported in the abandons of the view preceding the given one by the given members.
+func (d *DKG) latestLock(st *stage, view uint16, pids []uint16) (*lock, error) {
+	var latest *lock
+	for _, pid := range pids {
+		l, err := d.decodeLock(st, view-1, d.rmc.Data(newID(st.abandon, view-1, pid)))
+		if err != nil {
+			return nil, err
+		}
+		if l != nil && (latest == nil || l.view > latest.view) {
+			latest = l
+		}
+	}
+	return latest, nil
+}
+
+// encodeLock encodes the lock reported when abandoning a view as the view of the locked proposal, 2 bytes as uint16.
+// It is empty if there is no lock. The locked proposal itself was forwarded to everyone when locking on it.
+func encodeLock(l *lock) []byte {
+	if l == nil {
+		return nil
+	}
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data, l.view)
+	return data
+}
+
+// decodeLock decodes the lock reported when abandoning the given view, waiting until the locked proposal finishes.
+func (d *DKG) decodeLock(st *stage, view uint16, data []byte) (*lock, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) != 2 {
+		return nil, errors.New("wrong length of lock")
+	}
+	l := &lock{view: binary.LittleEndian.Uint16(data)}
+	if l.view > view {
+		return nil, errors.New("lock from a later view")
+	}
+	proposalID := newID(st.proposal, l.view, d.coordinatorOf(l.view))
+	if !d.await(d.conf.Timeout, proposalID) {
+		return nil, errors.New("locked proposal did not finish on time")
+	}
+	var err error
+	l.chosen, _, err = decodeProposal(d.rmc.Data(proposalID), d.nProc)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// subjectIDs returns the ids of the multicasts of the subject kind by the given members.
+func (d *DKG) subjectIDs(st *stage, pids []uint16) []uint64 {
+	ids := make([]uint64, len(pids))
+	for i, pid := range pids {
+		ids[i] = newID(st.subject, 0, pid)
+	}
+	return ids
+}
+
+// findComplaints returns the complaints about the dealings in which our share is incorrect.
+func (d *DKG) findComplaints(dealers []uint16) []*tss.Complaint {
+	var result []*tss.Complaint
+	for _, dealer := range dealers {
+		_, ok, err := tss.Decode(d.rmc.Data(newID(dealingKind, 0, dealer)), dealer, d.pid, d.p2pKeys[dealer])
+		if err != nil || !ok {
+			result = append(result, tss.NewComplaint(dealer, d.pid, d.conf.P2PSecretKey, d.conf.P2PPublicKeys[dealer]))
+		}
+	}
+	return result
+}
+
+// result judges the complaints and combines the dealings of the dealers that were not proven faulty.
+func (d *DKG) result(dealers, accusers []uint16) (*tss.WeakThresholdKey, error) {
+	tks := map[uint16]*tss.ThresholdKey{}
+	for _, dealer := range dealers {
+		tk, _, err := tss.Decode(d.rmc.Data(newID(dealingKind, 0, dealer)), dealer, d.pid, d.p2pKeys[dealer])
+		if err != nil {
+			return nil, err
+		}
+		tks[dealer] = tk
+	}
+
+	faulty := map[uint16]bool{}
+	shareProviders := map[uint16]bool{}
+	for _, accuser := range accusers {
+		complaints, err := decodeComplaints(d.rmc.Data(newID(complaintKind, 0, accuser)), accuser, d.nProc)
+		if err != nil {
+			return nil, err
+		}
+		honest := true
+		for _, c := range complaints {
+			tk, ok := tks[c.Dealer()]
+			if !ok {
+				continue
+			}
+			culprit, err := c.Verify(tk, d.conf.P2PPublicKeys)
+			if err != nil {
+				return nil, err
+			}
+			if culprit == c.Dealer() {
+				faulty[culprit] = true
+			} else {
+				honest = false
+			}
+		}
+		if honest {
+			shareProviders[accuser] = true
+		}
+	}
+
+	var chosen []uint16
+	for dealer := range tks {
+		if !faulty[dealer] {
+			chosen = append(chosen, dealer)
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, errors.New("all dealers are faulty")
+	}
+	if len(shareProviders) < int(d.threshold) {
+		return nil, errors.New("too few share providers")
+	}
+	sort.Slice(chosen, func(i, j int) bool { return chosen[i] < chosen[j] })
+	result := make([]*tss.ThresholdKey, len(chosen))
+	for i, dealer := range chosen {
+		result[i] = tks[dealer]
+	}
+	return tss.CreateWTK(result, shareProviders), nil
+}
+
+// await waits until all the multicasts with the given ids finish. It returns false on timeout.
+func (d *DKG) await(timeout time.Duration, ids ...uint64) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		done := true
+		for _, id := range ids {
+			if d.rmc.Status(id) != rmcbox.Finished {
+				done = false
+				break
+			}
+		}
+		if done {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// awaitAll waits until multicasts of the given kind from all members finish, or the timeout passes.
+// It returns the members whose multicasts finished.
+func (d *DKG) awaitAll(kind byte) []uint16 {
+	ids := make([]uint64, d.nProc)
+	for pid := range ids {
+		ids[pid] = newID(kind, 0, uint16(pid))
+	}
+	d.await(d.conf.Timeout, ids...)
+	return d.finished(kind, 0)
+}
+
+// finished returns the members whose multicasts of the given kind in the given view finished.
+func (d *DKG) finished(kind byte, view uint16) []uint16 {
+	var result []uint16
+	for pid := uint16(0); pid < d.nProc; pid++ {
+		if d.rmc.Status(newID(kind, view, pid)) == rmcbox.Finished {
+			result = append(result, pid)
+		}
+	}
+	return result
+}
@@ -0,0 +1,13 @@
+package dkg_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDKG(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DKG Suite")
+}
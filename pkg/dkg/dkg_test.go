@@ -0,0 +1,191 @@
+package dkg_test
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto"
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/p2p"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+	. "gitlab.com/alephledger/core-go/pkg/dkg"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	"gitlab.com/alephledger/core-go/pkg/tests"
+)
+
+var _ = Describe("DKG", func() {
+	var (
+		n        uint16
+		confs    []Config
+		netservs []network.Server
+		running  map[uint16]bool
+		setups   map[uint16]func(*DKG)
+		wtks     []*tss.WeakThresholdKey
+		errs     []error
+	)
+	BeforeEach(func() {
+		n = 4
+		pubs := make([]*bn256.VerificationKey, n)
+		privs := make([]*bn256.SecretKey, n)
+		p2pPubs := make([]*p2p.PublicKey, n)
+		p2pSecs := make([]*p2p.SecretKey, n)
+		for i := range pubs {
+			var err error
+			pubs[i], privs[i], err = bn256.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+			p2pPubs[i], p2pSecs[i], err = p2p.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+		}
+		confs = make([]Config, n)
+		running = map[uint16]bool{}
+		setups = map[uint16]func(*DKG){}
+		for i := range confs {
+			confs[i] = Config{
+				Pid:           uint16(i),
+				PublicKeys:    pubs,
+				PrivateKey:    privs[i],
+				P2PPublicKeys: p2pPubs,
+				P2PSecretKey:  p2pSecs[i],
+				Timeout:       2 * time.Second,
+			}
+			running[uint16(i)] = true
+		}
+		netservs = tests.NewNetwork(int(n), 500*time.Millisecond)
+	})
+	run := func() {
+		wtks = make([]*tss.WeakThresholdKey, n)
+		errs = make([]error, n)
+		var wg sync.WaitGroup
+		for pid := range running {
+			wg.Add(1)
+			go func(pid uint16) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				d, err := New(confs[pid], netservs[pid])
+				Expect(err).NotTo(HaveOccurred())
+				if setup, ok := setups[pid]; ok {
+					setup(d)
+				}
+				wtks[pid], errs[pid] = d.Run()
+			}(pid)
+		}
+		wg.Wait()
+		tests.CloseNetwork(netservs)
+	}
+	// checkAgreement checks that all the running members obtained the same key, and that it can be used for signing.
+	checkAgreement := func() {
+		msg := []byte("19890604")
+		var ref *tss.WeakThresholdKey
+		for pid := range running {
+			Expect(errs[pid]).NotTo(HaveOccurred())
+			if ref == nil {
+				ref = wtks[pid]
+			}
+			Expect(wtks[pid].GlobalVK().Marshal()).To(Equal(ref.GlobalVK().Marshal()))
+			Expect(wtks[pid].ShareProviders()).To(Equal(ref.ShareProviders()))
+		}
+		Expect(len(ref.ShareProviders())).To(BeNumerically(">=", int(ref.Threshold())))
+		var shares []*tss.Share
+		for pid := range running {
+			sh := wtks[pid].CreateShare(msg)
+			if !ref.ShareProviders()[pid] {
+				Expect(sh).To(BeNil())
+				continue
+			}
+			Expect(ref.VerifyShare(sh, msg)).To(BeTrue())
+			shares = append(shares, sh)
+		}
+		sgn, ok := ref.CombineShares(shares)
+		Expect(ok).To(BeTrue())
+		Expect(ref.VerifySignature(sgn, msg)).To(BeTrue())
+	}
+	Context("When all members are honest", func() {
+		It("should produce the same key for everyone", func() {
+			run()
+			checkAgreement()
+			for pid := uint16(0); pid < n; pid++ {
+				Expect(wtks[0].ShareProviders()[pid]).To(BeTrue())
+			}
+		})
+	})
+	Context("When one member is down", func() {
+		It("should produce the same key for the others", func() {
+			delete(running, 3)
+			run()
+			checkAgreement()
+			Expect(wtks[0].ShareProviders()[3]).To(BeFalse())
+		})
+	})
+	Context("When a dealer sends an incorrect share", func() {
+		It("should exclude the dealer and keep the victim as a share provider", func() {
+			victim := uint16(1)
+			setups[2] = func(d *DKG) {
+				d.SetDealing(func(keys []encrypt.SymmetricKey) (*tss.ThresholdKey, error) {
+					bad := make([]encrypt.SymmetricKey, len(keys))
+					copy(bad, keys)
+					junk := make([]byte, 32)
+					rand.Read(junk)
+					key, err := encrypt.NewSymmetricKey(junk)
+					if err != nil {
+						return nil, err
+					}
+					bad[victim] = key
+					return tss.NewRandom(n, crypto.MinimalTrusted(n)).Encrypt(2, bad)
+				})
+			}
+			run()
+			checkAgreement()
+			Expect(wtks[0].ShareProviders()[victim]).To(BeTrue())
+		})
+	})
+	Context("When a dealer uses a wrong threshold", func() {
+		It("should produce the same key for everyone", func() {
+			setups[3] = func(d *DKG) {
+				d.SetDealing(func(keys []encrypt.SymmetricKey) (*tss.ThresholdKey, error) {
+					return tss.NewRandom(n, crypto.MinimalTrusted(n)+1).Encrypt(3, keys)
+				})
+			}
+			run()
+			checkAgreement()
+		})
+	})
+	Context("When a member complains falsely about an honest dealer", func() {
+		It("should keep the dealer and exclude the accuser from share providers", func() {
+			accuser, dealer := uint16(3), uint16(1)
+			setups[accuser] = func(d *DKG) {
+				d.SetComplaints(func([]uint16) []*tss.Complaint {
+					return []*tss.Complaint{tss.NewComplaint(dealer, accuser, confs[accuser].P2PSecretKey, confs[accuser].P2PPublicKeys[dealer])}
+				})
+			}
+			run()
+			checkAgreement()
+			Expect(wtks[0].ShareProviders()[accuser]).To(BeFalse())
+			Expect(wtks[0].ShareProviders()[dealer]).To(BeTrue())
+		})
+	})
+	Context("When the first coordinator proposes no accusers", func() {
+		It("should reject the proposal and agree with the next coordinator", func() {
+			setups[0] = func(d *DKG) {
+				d.SetAccusers(func([]uint16) []uint16 { return nil })
+			}
+			run()
+			checkAgreement()
+			for pid := uint16(0); pid < n; pid++ {
+				Expect(wtks[0].ShareProviders()[pid]).To(BeTrue())
+			}
+		})
+	})
+	Context("When the first coordinator is down", func() {
+		It("should agree with the next coordinator", func() {
+			delete(running, 0)
+			run()
+			checkAgreement()
+			Expect(wtks[1].ShareProviders()[0]).To(BeFalse())
+		})
+	})
+})
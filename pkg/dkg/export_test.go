@@ -0,0 +1,21 @@
+package dkg
+
+import (
+	"gitlab.com/alephledger/core-go/pkg/crypto/encrypt"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+)
+
+// SetDealing replaces the dealing of the member, to simulate a byzantine dealer.
+func (d *DKG) SetDealing(dealing func(keys []encrypt.SymmetricKey) (*tss.ThresholdKey, error)) {
+	d.dealing = dealing
+}
+
+// SetComplaints replaces the complaints of the member about the given dealers, to simulate a byzantine accuser.
+func (d *DKG) SetComplaints(complaints func(dealers []uint16) []*tss.Complaint) {
+	d.complaints = complaints
+}
+
+// SetAccusers replaces the accusers the member proposes as a coordinator, to simulate a byzantine coordinator.
+func (d *DKG) SetAccusers(accusers func(chosen []uint16) []uint16) {
+	d.accusers = accusers
+}
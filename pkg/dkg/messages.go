@@ -0,0 +1,139 @@
+package dkg
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+)
+
+// Kinds of multicasts performed during the protocol.
+const (
+	dealingKind byte = iota
+	complaintKind
+	// Kinds of multicasts agreeing on the dealings.
+	dealingProposalKind
+	dealingCommitKind
+	dealingAbandonKind
+	// Kinds of multicasts agreeing on the complaints.
+	complaintProposalKind
+	complaintCommitKind
+	complaintAbandonKind
+)
+
+// newID returns the id of the multicast of the given kind started by pid in the given view.
+func newID(kind byte, view, pid uint16) uint64 {
+	return uint64(kind)<<32 | uint64(view)<<16 | uint64(pid)
+}
+
+func kindOf(id uint64) byte {
+	return byte(id >> 32)
+}
+
+func viewOf(id uint64) uint16 {
+	return uint16(id >> 16)
+}
+
+func originOf(id uint64) uint16 {
+	return uint16(id)
+}
+
+// encodeComplaints encodes complaints as a concatenation of their marshalled forms.
+func encodeComplaints(complaints []*tss.Complaint) []byte {
+	data := make([]byte, 0, len(complaints)*tss.ComplaintLength)
+	for _, c := range complaints {
+		data = append(data, c.Marshal()...)
+	}
+	return data
+}
+
+// decodeComplaints decodes complaints of the given accuser.
+func decodeComplaints(data []byte, accuser, nProc uint16) ([]*tss.Complaint, error) {
+	if len(data)%tss.ComplaintLength != 0 {
+		return nil, errors.New("wrong length of complaints")
+	}
+	result := make([]*tss.Complaint, len(data)/tss.ComplaintLength)
+	for i := range result {
+		result[i] = new(tss.Complaint)
+		if err := result[i].Unmarshal(data[i*tss.ComplaintLength : (i+1)*tss.ComplaintLength]); err != nil {
+			return nil, err
+		}
+		if result[i].Dealer() >= nProc {
+			return nil, errors.New("dealer out of range")
+		}
+		if result[i].Accuser() != accuser {
+			return nil, errors.New("complaint made by a different accuser")
+		}
+	}
+	return result, nil
+}
+
+// encodePids encodes a list of pids, each as 2 bytes of uint16.
+func encodePids(pids []uint16) []byte {
+	data := make([]byte, 2*len(pids))
+	for i, pid := range pids {
+		binary.LittleEndian.PutUint16(data[2*i:], pid)
+	}
+	return data
+}
+
+// encodeProposal encodes a proposal in the following form
+// (1) number of chosen members, 2 bytes as uint16
+// (2) chosen members, encoded with encodePids
+// (3) members whose abandons of the previous view justify the proposal, encoded with encodePids
+func encodeProposal(chosen, justification []uint16) []byte {
+	data := make([]byte, 2, 2+2*len(chosen)+2*len(justification))
+	binary.LittleEndian.PutUint16(data, uint16(len(chosen)))
+	data = append(data, encodePids(chosen)...)
+	return append(data, encodePids(justification)...)
+}
+
+// decodeProposal decodes a proposal encoded with encodeProposal.
+func decodeProposal(data []byte, nProc uint16) (chosen, justification []uint16, err error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("proposal too short")
+	}
+	n := 2 + 2*int(binary.LittleEndian.Uint16(data))
+	if len(data) < n {
+		return nil, nil, errors.New("proposal too short")
+	}
+	chosen, err = decodePids(data[2:n], nProc)
+	if err != nil {
+		return nil, nil, err
+	}
+	justification, err = decodePids(data[n:], nProc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chosen, justification, nil
+}
+
+// decodePids decodes a list of distinct pids in increasing order.
+func decodePids(data []byte, nProc uint16) ([]uint16, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("wrong length of pid list")
+	}
+	result := make([]uint16, len(data)/2)
+	for i := range result {
+		result[i] = binary.LittleEndian.Uint16(data[2*i:])
+		if result[i] >= nProc {
+			return nil, errors.New("pid out of range")
+		}
+		if i > 0 && result[i] <= result[i-1] {
+			return nil, errors.New("pids not in increasing order")
+		}
+	}
+	return result, nil
+}
+
+func equalPids(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package dkg
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto"
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	"gitlab.com/alephledger/core-go/pkg/rmcbox"
+)
+
+// Types of messages exchanged between members.
+const (
+	// sendData starts a multicast: data is sent, a signature is returned, and the proof follows if the multicast succeeds.
+	sendData byte = iota
+	// sendFinished forwards data together with the proof that it was multicast successfully.
+	sendFinished
+)
+
+const pollInterval = 10 * time.Millisecond
+
+// listen accepts incoming connections until Run returns.
+func (d *DKG) listen() {
+	for {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+		conn, err := d.netserv.Listen()
+		if err != nil {
+			continue
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *DKG) handle(conn network.Connection) {
+	defer conn.Close()
+	pid, id, msgType, err := rmcbox.AcceptGreeting(conn)
+	if err != nil || originOf(id) >= d.nProc {
+		return
+	}
+	switch msgType {
+	case sendData:
+		if pid != originOf(id) {
+			return
+		}
+		data, err := d.rmc.AcceptData(id, pid, conn)
+		if err != nil {
+			return
+		}
+		if d.validate(id, data) != nil {
+			return
+		}
+		if d.rmc.SendSignature(id, conn) != nil || conn.Flush() != nil {
+			return
+		}
+		d.rmc.AcceptProof(id, conn)
+	case sendFinished:
+		d.rmc.AcceptFinished(id, originOf(id), conn)
+	}
+}
+
+// validate checks data multicast with the given id before signing it.
+func (d *DKG) validate(id uint64, data []byte) error {
+	origin, view := originOf(id), viewOf(id)
+	switch kindOf(id) {
+	case dealingKind:
+		if view != 0 {
+			return errors.New("dealing in a view other than the first")
+		}
+		tk, _, err := tss.Decode(data, origin, d.pid, d.p2pKeys[origin])
+		if err != nil {
+			return err
+		}
+		if tk.NProc() != d.nProc || tk.Threshold() != d.threshold {
+			return errors.New("wrong parameters of dealing")
+		}
+		if !tk.VerifyPublicKeys() {
+			return errors.New("inconsistent verification keys in dealing")
+		}
+		return nil
+	case complaintKind:
+		if view != 0 {
+			return errors.New("complaints in a view other than the first")
+		}
+		_, err := decodeComplaints(data, origin, d.nProc)
+		return err
+	}
+	st, ok := d.stages[kindOf(id)]
+	if !ok {
+		return errors.New("unknown kind of multicast")
+	}
+	switch kindOf(id) {
+	case st.proposal:
+		if origin != d.coordinatorOf(view) {
+			return errors.New("not a coordinator")
+		}
+		return d.validateProposal(st, view, data)
+	case st.commit:
+		if len(data) != 0 {
+			return errors.New("commit with data")
+		}
+		return nil
+	case st.abandon:
+		_, err := d.decodeLock(st, view, data)
+		return err
+	}
+	return errors.New("unknown kind of multicast")
+}
+
+// validateProposal checks that the proposal in the given view chooses enough members whose multicasts finished,
+// and that it repeats the latest lock reported in its justification.
+func (d *DKG) validateProposal(st *stage, view uint16, data []byte) error {
+	chosen, justification, err := decodeProposal(data, d.nProc)
+	if err != nil {
+		return err
+	}
+	if len(chosen) < st.min {
+		return errors.New("too few members chosen")
+	}
+	if view > 0 {
+		if len(justification) < int(crypto.MinimalQuorum(d.nProc)) {
+			return errors.New("too few abandons of the previous view")
+		}
+		ids := make([]uint64, len(justification))
+		for i, pid := range justification {
+			ids[i] = newID(st.abandon, view-1, pid)
+		}
+		if !d.await(d.conf.Timeout, ids...) {
+			return errors.New("abandons of the previous view did not finish on time")
+		}
+		latest, err := d.latestLock(st, view, justification)
+		if err != nil {
+			return err
+		}
+		if latest != nil && !equalPids(latest.chosen, chosen) {
+			return errors.New("proposal differs from the latest lock")
+		}
+	}
+	if !d.await(d.conf.Timeout, d.subjectIDs(st, chosen)...) {
+		return errors.New("chosen multicasts did not finish on time")
+	}
+	return nil
+}
+
+// multicast performs a reliable multicast of data with the given id in the background.
+// The proof is sent to everyone as soon as enough signatures are gathered.
+func (d *DKG) multicast(id uint64, data []byte) {
+	done := make(chan struct{})
+	timeout := time.After(d.conf.Timeout)
+	for pid := uint16(0); pid < d.nProc; pid++ {
+		if pid == d.pid {
+			continue
+		}
+		d.wg.Add(1)
+		go func(pid uint16) {
+			defer d.wg.Done()
+			conn, err := d.netserv.Dial(pid)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if rmcbox.Greet(conn, d.pid, id, sendData) != nil {
+				return
+			}
+			if d.rmc.SendData(id, data, conn) != nil || conn.Flush() != nil {
+				return
+			}
+			finished, err := d.rmc.AcceptSignature(id, pid, conn)
+			if err != nil {
+				return
+			}
+			if finished {
+				close(done)
+			}
+			select {
+			case <-done:
+			case <-timeout:
+				return
+			}
+			if d.rmc.SendProof(id, conn) == nil {
+				conn.Flush()
+			}
+		}(pid)
+	}
+}
+
+// forward sends the data and proof of a finished multicast to everyone except its originator, in the background.
+func (d *DKG) forward(id uint64) {
+	for pid := uint16(0); pid < d.nProc; pid++ {
+		if pid == d.pid || pid == originOf(id) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(pid uint16) {
+			defer d.wg.Done()
+			conn, err := d.netserv.Dial(pid)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if rmcbox.Greet(conn, d.pid, id, sendFinished) != nil {
+				return
+			}
+			if d.rmc.SendFinished(id, conn) == nil {
+				conn.Flush()
+			}
+		}(pid)
+	}
+}
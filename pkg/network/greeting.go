@@ -2,6 +2,7 @@ package network
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
 )
 
@@ -14,7 +15,13 @@ func Greet(w io.Writer, pid uint16, sid uint64) error {
 	return err
 }
 
+// authenticated is implemented by connections that know the pid of their other end.
+type authenticated interface {
+	RemotePid() uint16
+}
+
 // AcceptGreeting accepts a greeting and returns the information it learned from it.
+// If conn knows the authenticated pid of its other end, a greeting claiming a different pid is rejected.
 func AcceptGreeting(conn io.Reader) (pid uint16, sid uint64, err error) {
 	var data [10]byte
 	_, err = io.ReadFull(conn, data[:])
@@ -23,5 +30,8 @@ func AcceptGreeting(conn io.Reader) (pid uint16, sid uint64, err error) {
 	}
 	pid = binary.LittleEndian.Uint16(data[0:])
 	sid = binary.LittleEndian.Uint64(data[2:])
+	if a, ok := conn.(authenticated); ok && a.RemotePid() != pid {
+		err = errors.New("greeting from a pid other than the authenticated one")
+	}
 	return
 }
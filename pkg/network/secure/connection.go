@@ -0,0 +1,152 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"gitlab.com/alephledger/core-go/pkg/network"
+)
+
+// maxFrame is the maximal length of plaintext sent in a single frame.
+const maxFrame = 1 << 16
+
+type connection struct {
+	network.Connection
+	remote uint16
+
+	readMx    sync.Mutex
+	readAEAD  cipher.AEAD
+	readCount uint64
+	plain     []byte
+
+	writeMx    sync.Mutex
+	writeAEAD  cipher.AEAD
+	writeCount uint64
+	pending    []byte
+}
+
+func newConnection(conn network.Connection, remote uint16, writeKey, readKey []byte) (*connection, error) {
+	writeAEAD, err := newAEAD(writeKey)
+	if err != nil {
+		return nil, err
+	}
+	readAEAD, err := newAEAD(readKey)
+	if err != nil {
+		return nil, err
+	}
+	return &connection{
+		Connection: conn,
+		remote:     remote,
+		readAEAD:   readAEAD,
+		writeAEAD:  writeAEAD,
+	}, nil
+}
+
+// RemotePid returns the authenticated pid of the other end of the connection.
+func (c *connection) RemotePid() uint16 {
+	return c.remote
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of the frame with the given number. Every direction uses a different key,
+// so numbering frames from zero in both directions is safe.
+func nonce(aead cipher.AEAD, count uint64) []byte {
+	result := make([]byte, aead.NonceSize())
+	binary.LittleEndian.PutUint64(result, count)
+	return result
+}
+
+// Read decrypts data from the incoming frames.
+func (c *connection) Read(buf []byte) (int, error) {
+	c.readMx.Lock()
+	defer c.readMx.Unlock()
+	for len(c.plain) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(buf, c.plain)
+	c.plain = c.plain[n:]
+	return n, nil
+}
+
+// readFrame reads a single frame in the form
+// (1) length of the ciphertext, 4 bytes as uint32
+// (2) ciphertext
+func (c *connection) readFrame() error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.Connection, header); err != nil {
+		return err
+	}
+	length := binary.LittleEndian.Uint32(header)
+	if length > maxFrame+uint32(c.readAEAD.Overhead()) {
+		return errors.New("frame too long")
+	}
+	ct := make([]byte, length)
+	if _, err := io.ReadFull(c.Connection, ct); err != nil {
+		return err
+	}
+	plain, err := c.readAEAD.Open(ct[:0], nonce(c.readAEAD, c.readCount), ct, header)
+	if err != nil {
+		return err
+	}
+	c.readCount++
+	c.plain = plain
+	return nil
+}
+
+// Write buffers the data, sending full frames as soon as possible. The rest is sent by Flush.
+func (c *connection) Write(buf []byte) (int, error) {
+	c.writeMx.Lock()
+	defer c.writeMx.Unlock()
+	c.pending = append(c.pending, buf...)
+	for len(c.pending) >= maxFrame {
+		if err := c.writeFrame(c.pending[:maxFrame]); err != nil {
+			return 0, err
+		}
+		c.pending = c.pending[maxFrame:]
+	}
+	return len(buf), nil
+}
+
+// Flush sends all the buffered data.
+func (c *connection) Flush() error {
+	c.writeMx.Lock()
+	defer c.writeMx.Unlock()
+	if len(c.pending) > 0 {
+		if err := c.writeFrame(c.pending); err != nil {
+			return err
+		}
+		c.pending = nil
+	}
+	return c.Connection.Flush()
+}
+
+func (c *connection) writeFrame(plain []byte) error {
+	header := make([]byte, 4, 4+len(plain)+c.writeAEAD.Overhead())
+	binary.LittleEndian.PutUint32(header, uint32(len(plain)+c.writeAEAD.Overhead()))
+	frame := c.writeAEAD.Seal(header, nonce(c.writeAEAD, c.writeCount), plain, header)
+	c.writeCount++
+	_, err := c.Connection.Write(frame)
+	return err
+}
+
+// Close flushes the buffered data and closes the connection.
+func (c *connection) Close() error {
+	err := c.Flush()
+	if err2 := c.Connection.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package secure_test
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	"gitlab.com/alephledger/core-go/pkg/network/persistent"
+	. "gitlab.com/alephledger/core-go/pkg/network/secure"
+	"gitlab.com/alephledger/core-go/pkg/tests"
+)
+
+var _ = Describe("Secure", func() {
+	var (
+		n       uint16
+		pubs    []*bn256.VerificationKey
+		privs   []*bn256.SecretKey
+		raw     []network.Server
+		servers []network.Server
+	)
+	BeforeEach(func() {
+		n = 3
+		pubs = make([]*bn256.VerificationKey, n)
+		privs = make([]*bn256.SecretKey, n)
+		for i := range pubs {
+			var err error
+			pubs[i], privs[i], err = bn256.GenerateKeys()
+			Expect(err).NotTo(HaveOccurred())
+		}
+		raw = tests.NewNetwork(int(n), time.Second)
+		servers = make([]network.Server, n)
+		for i := uint16(0); i < n; i++ {
+			var err error
+			servers[i], err = NewServer(raw[i], i, privs[i], pubs, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+	AfterEach(func() {
+		for _, s := range servers {
+			s.Stop()
+		}
+		tests.CloseNetwork(raw)
+	})
+	// listen accepts a single connection on the given server in the background.
+	listen := func(s network.Server) (chan network.Connection, chan error) {
+		conns := make(chan network.Connection, 1)
+		errs := make(chan error, 1)
+		go func() {
+			conn, err := s.Listen()
+			conns <- conn
+			errs <- err
+		}()
+		return conns, errs
+	}
+	It("should authenticate both ends and exchange data", func() {
+		conns, errs := listen(servers[1])
+		out, err := servers[0].Dial(1)
+		Expect(err).NotTo(HaveOccurred())
+		in := <-conns
+		Expect(<-errs).NotTo(HaveOccurred())
+		pid, ok := RemotePid(out)
+		Expect(ok).To(BeTrue())
+		Expect(pid).To(Equal(uint16(1)))
+		pid, ok = RemotePid(in)
+		Expect(ok).To(BeTrue())
+		Expect(pid).To(Equal(uint16(0)))
+
+		big := make([]byte, 200000)
+		rand.Read(big)
+		go func() {
+			defer GinkgoRecover()
+			_, err := out.Write(big)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.Flush()).To(Succeed())
+		}()
+		received := make([]byte, len(big))
+		_, err = io.ReadFull(in, received)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(Equal(big))
+
+		go func() {
+			defer GinkgoRecover()
+			in.Write([]byte("reply"))
+			Expect(in.Flush()).To(Succeed())
+		}()
+		reply := make([]byte, 5)
+		_, err = io.ReadFull(out, reply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply).To(Equal([]byte("reply")))
+	})
+	It("should accept other connections while a handshake is stalled", func() {
+		conns, errs := listen(servers[0])
+		stalled, err := raw[2].Dial(0)
+		Expect(err).NotTo(HaveOccurred())
+		defer stalled.Close()
+		out, err := servers[1].Dial(0)
+		Expect(err).NotTo(HaveOccurred())
+		defer out.Close()
+		in := <-conns
+		Expect(<-errs).NotTo(HaveOccurred())
+		pid, ok := RemotePid(in)
+		Expect(ok).To(BeTrue())
+		Expect(pid).To(Equal(uint16(1)))
+	})
+	It("should reject a greeting claiming a pid other than the authenticated one", func() {
+		conns, errs := listen(servers[1])
+		out, err := servers[0].Dial(1)
+		Expect(err).NotTo(HaveOccurred())
+		in := <-conns
+		Expect(<-errs).NotTo(HaveOccurred())
+		go func() {
+			network.Greet(out, 2, 7)
+			out.Flush()
+		}()
+		_, _, err = network.AcceptGreeting(in)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should not be created with a secret key of a different pid", func() {
+		_, err := NewServer(raw[2], 1, privs[2], pubs, time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject a peer impersonating a different pid", func() {
+		conns, errs := listen(servers[0])
+		conn, err := raw[2].Dial(0)
+		Expect(err).NotTo(HaveOccurred())
+		hello := make([]byte, 34)
+		binary.LittleEndian.PutUint16(hello, 1)
+		rand.Read(hello[2:])
+		conn.Write(hello)
+		conn.Flush()
+		response := make([]byte, 34+bn256.SignatureLength)
+		_, err = io.ReadFull(conn, response)
+		Expect(err).NotTo(HaveOccurred())
+		transcript := append(append([]byte("secure-initiator"), hello...), response[:34]...)
+		conn.Write(privs[2].Sign(transcript).Marshal())
+		conn.Flush()
+		Expect(<-conns).To(BeNil())
+		Expect(<-errs).To(HaveOccurred())
+	})
+	It("should reject a responder with an unexpected key", func() {
+		otherPubs := append([]*bn256.VerificationKey{}, pubs...)
+		otherPubs[1], _, _ = bn256.GenerateKeys()
+		dialer, err := NewServer(raw[0], 0, privs[0], otherPubs, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		go servers[1].Listen()
+		_, err = dialer.Dial(1)
+		Expect(err).To(HaveOccurred())
+	})
+	Context("Over persistent connections", func() {
+		var (
+			persistentRaw []network.Server
+			stops         []func()
+		)
+		BeforeEach(func() {
+			addrs := make([]string, 2)
+			for i := range addrs {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).NotTo(HaveOccurred())
+				addrs[i] = ln.Addr().String()
+				ln.Close()
+			}
+			persistentRaw = make([]network.Server, 2)
+			stops = nil
+			for i := range addrs {
+				netserv, service, err := persistent.NewServer(addrs[i], addrs, time.Second)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(service.Start()).To(Succeed())
+				persistentRaw[i] = netserv
+				stops = append(stops, service.Stop)
+			}
+		})
+		AfterEach(func() {
+			for _, stop := range stops {
+				stop()
+			}
+		})
+		It("should give up dialing a peer stalling the handshake", func() {
+			dialer, err := NewServer(persistentRaw[0], 0, privs[0], pubs, 200*time.Millisecond)
+			Expect(err).NotTo(HaveOccurred())
+			go func() {
+				conn, err := persistentRaw[1].Listen()
+				if err == nil {
+					defer conn.Close()
+					time.Sleep(2 * time.Second)
+				}
+			}()
+			done := make(chan error, 1)
+			go func() {
+				_, err := dialer.Dial(1)
+				done <- err
+			}()
+			Eventually(done, time.Second).Should(Receive(HaveOccurred()))
+		})
+		It("should close an incoming connection stalling the handshake", func() {
+			listener, err := NewServer(persistentRaw[0], 0, privs[0], pubs, 200*time.Millisecond)
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Stop()
+			go listener.Listen()
+			stalled, err := persistentRaw[1].Dial(0)
+			Expect(err).NotTo(HaveOccurred())
+			defer stalled.Close()
+			stalled.Write([]byte{1})
+			Expect(stalled.Flush()).To(Succeed())
+			done := make(chan error, 1)
+			go func() {
+				_, err := stalled.Read(make([]byte, 1))
+				done <- err
+			}()
+			Eventually(done, time.Second).Should(Receive(HaveOccurred()))
+		})
+	})
+})
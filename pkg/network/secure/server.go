@@ -0,0 +1,237 @@
+// Package secure implements a network.Server decorator that authenticates and encrypts all connections.
+//
+// Every connection starts with a handshake, in which both ends exchange ephemeral X25519 keys
+// and sign the transcript with their bn256 committee keys. Connections from peers whose signature does not
+// match the claimed pid are rejected. The session keys are derived from the ephemeral keys only,
+// so compromising committee keys later does not reveal past traffic.
+// Afterwards all the data is sent in AEAD frames.
+//
+// Incoming handshakes run concurrently, so a peer stalling its handshake does not delay others.
+// The wrapped server has to provide bidirectional connections.
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/sha3"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/network"
+)
+
+type server struct {
+	wrapped    network.Server
+	pid        uint16
+	priv       *bn256.SecretKey
+	pubs       []*bn256.VerificationKey
+	timeout    time.Duration
+	accepted   chan network.Connection
+	listenOnce sync.Once
+	stopOnce   sync.Once
+	quit       chan struct{}
+}
+
+// NewServer wraps the given server, so that it authenticates all connections with the committee keys
+// and encrypts all the data sent through them. The handshake of every connection has to finish within the timeout,
+// which also bounds the time Listen waits for an authenticated connection.
+func NewServer(wrapped network.Server, pid uint16, priv *bn256.SecretKey, pubs []*bn256.VerificationKey, timeout time.Duration) (network.Server, error) {
+	if int(pid) >= len(pubs) {
+		return nil, errors.New("pid out of range")
+	}
+	if !bn256.VerifyKeys(pubs[pid], priv) {
+		return nil, errors.New("secret key does not match the public key of pid")
+	}
+	return &server{
+		wrapped:  wrapped,
+		pid:      pid,
+		priv:     priv,
+		pubs:     pubs,
+		timeout:  timeout,
+		accepted: make(chan network.Connection),
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+func (s *server) Dial(pid uint16) (network.Connection, error) {
+	if int(pid) >= len(s.pubs) {
+		return nil, errors.New("pid out of range")
+	}
+	conn, err := s.wrapped.Dial(pid)
+	if err != nil {
+		return nil, err
+	}
+	return s.handshake(conn, true, pid)
+}
+
+func (s *server) Listen() (network.Connection, error) {
+	s.listenOnce.Do(func() { go s.accept() })
+	select {
+	case conn := <-s.accepted:
+		return conn, nil
+	case <-s.quit:
+		return nil, errors.New("server stopped")
+	case <-time.After(s.timeout):
+		return nil, errors.New("Listen timed out")
+	}
+}
+
+// accept listens on the wrapped server and handshakes every incoming connection in a separate goroutine.
+// Authenticated connections are handed over to Listen, the others are dropped.
+func (s *server) accept() {
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		conn, err := s.wrapped.Listen()
+		if err != nil {
+			continue
+		}
+		go func() {
+			result, err := s.handshake(conn, false, 0)
+			if err != nil {
+				return
+			}
+			select {
+			case s.accepted <- result:
+			case <-s.quit:
+				result.Close()
+			}
+		}()
+	}
+}
+
+func (s *server) Stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+	s.wrapped.Stop()
+}
+
+// RemotePid returns the authenticated pid of the other end of a connection created by a secure server.
+func RemotePid(conn network.Connection) (uint16, bool) {
+	if c, ok := conn.(*connection); ok {
+		return c.RemotePid(), true
+	}
+	return 0, false
+}
+
+const (
+	keyLength   = 32
+	helloLength = 2 + keyLength
+)
+
+// handshake authenticates both ends of conn and derives the session keys. The messages are
+// (1) initiator's hello: its pid, 2 bytes as uint16, followed by its ephemeral public key
+// (2) responder's hello, followed by its signature of the transcript
+// (3) initiator's signature of the transcript
+// where the transcript consists of both hellos. The signatures of the two ends are made with different labels.
+// On failure, conn is closed. It is also closed when the handshake does not finish within the timeout,
+// as not every wrapped server can interrupt a blocked read.
+func (s *server) handshake(conn network.Connection, initiator bool, remote uint16) (network.Connection, error) {
+	timer := time.AfterFunc(s.timeout, func() { conn.Close() })
+	result, err := s.doHandshake(conn, initiator, remote)
+	if !timer.Stop() && err == nil {
+		err = errors.New("handshake timed out")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *server) doHandshake(conn network.Connection, initiator bool, remote uint16) (*connection, error) {
+	ephemeral := make([]byte, keyLength)
+	if _, err := io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return nil, err
+	}
+	ephemeralPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	ours := make([]byte, 2, helloLength)
+	binary.LittleEndian.PutUint16(ours, s.pid)
+	ours = append(ours, ephemeralPub...)
+	theirs := make([]byte, helloLength)
+
+	var transcript []byte
+	if initiator {
+		if err := send(conn, ours); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, theirs); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint16(theirs) != remote {
+			return nil, errors.New("wrong pid of the responder")
+		}
+		transcript = append(append([]byte{}, ours...), theirs...)
+		if err := s.acceptSignature(conn, remote, responderLabel, transcript); err != nil {
+			return nil, err
+		}
+		if err := send(conn, s.priv.Sign(append([]byte(initiatorLabel), transcript...)).Marshal()); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := io.ReadFull(conn, theirs); err != nil {
+			return nil, err
+		}
+		remote = binary.LittleEndian.Uint16(theirs)
+		if int(remote) >= len(s.pubs) {
+			return nil, errors.New("pid of the initiator out of range")
+		}
+		transcript = append(append([]byte{}, theirs...), ours...)
+		if err := send(conn, append(ours, s.priv.Sign(append([]byte(responderLabel), transcript...)).Marshal()...)); err != nil {
+			return nil, err
+		}
+		if err := s.acceptSignature(conn, remote, initiatorLabel, transcript); err != nil {
+			return nil, err
+		}
+	}
+
+	shared, err := curve25519.X25519(ephemeral, theirs[2:])
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]byte, 2*keyLength)
+	sha3.ShakeSum256(keys, append(append([]byte(sessionLabel), transcript...), shared...))
+	toResponder, toInitiator := keys[:keyLength], keys[keyLength:]
+	if initiator {
+		return newConnection(conn, remote, toResponder, toInitiator)
+	}
+	return newConnection(conn, remote, toInitiator, toResponder)
+}
+
+const (
+	initiatorLabel = "secure-initiator"
+	responderLabel = "secure-responder"
+	sessionLabel   = "secure-session"
+)
+
+func (s *server) acceptSignature(conn network.Connection, pid uint16, label string, transcript []byte) error {
+	data := make([]byte, bn256.SignatureLength)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return err
+	}
+	sgn, err := new(bn256.Signature).Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	if !s.pubs[pid].Verify(sgn, append([]byte(label), transcript...)) {
+		return errors.New("wrong handshake signature")
+	}
+	return nil
+}
+
+func send(conn network.Connection, data []byte) error {
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	return conn.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
 
@@ -11,6 +12,8 @@ import (
 	"gitlab.com/alephledger/core-go/pkg/crypto/multi"
 )
 
+// instance is the state of a single multicast. Its lock is never held while writing or reading,
+// so that two processes sending each other the same instance at once cannot block each other.
 type instance struct {
 	sync.Mutex
 	id         uint64
@@ -23,23 +26,28 @@ type instance struct {
 
 func (ins *instance) SendData(w io.Writer) error {
 	ins.Lock()
-	defer ins.Unlock()
-	err := encodeUint32(w, ins.rawLen)
+	rawLen, signedData := ins.rawLen, ins.signedData
+	ins.Unlock()
+	err := encodeUint32(w, rawLen)
 	if err != nil {
 		return err
 	}
-	_, err = w.Write(ins.signedData)
+	_, err = w.Write(signedData)
 	return err
 }
 
 func (ins *instance) SendProof(w io.Writer) error {
 	ins.Lock()
-	defer ins.Unlock()
 	if ins.stat != Finished {
+		ins.Unlock()
 		return errors.New("no proof to send")
 	}
-	_, err := w.Write(ins.proof.Marshal())
-	return err
+	data, err := ins.proof.EncodeCompressed(ins.keys.Length())
+	ins.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeProof(w, data)
 }
 
 func (ins *instance) SendFinished(w io.Writer) error {
@@ -58,31 +66,51 @@ func (ins *instance) AcceptSignature(pid uint16, r io.Reader) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if !ins.keys.Verify(pid, append(ins.signedData, signature...)) {
-		return false, errors.New("wrong signature")
+	if ins.stat == Finished {
+		return false, nil
 	}
-	if ins.stat != Finished {
-		done, err := ins.proof.Aggregate(pid, signature)
-		if done {
-			ins.stat = Finished
-			return true, err
-		}
+	if pid >= ins.keys.Length() {
+		return false, fmt.Errorf("signature from unknown process %v", pid)
+	}
+	// Signatures are verified only in the complete proof, and the incorrect ones are found when it fails.
+	done, err := ins.proof.Aggregate(pid, signature)
+	if err != nil || !done {
+		return false, err
+	}
+	if ins.keys.MultiVerify(ins.proof) {
+		ins.stat = Finished
+		return true, nil
+	}
+	bad, err := ins.keys.Diagnose(ins.proof)
+	if err != nil {
+		return false, err
+	}
+	if err := ins.proof.Remove(bad); err != nil {
 		return false, err
 	}
+	// Only the sender of the current signature is blamed, earlier senders were accepted already.
+	for _, b := range bad {
+		if b == pid {
+			return false, errors.New("wrong signature")
+		}
+	}
 	return false, nil
 }
 
 func (ins *instance) SendSignature(w io.Writer) error {
 	ins.Lock()
-	defer ins.Unlock()
 	if ins.stat == Unknown {
+		ins.Unlock()
 		return errors.New("cannot sign unknown data")
 	}
 	signature := ins.keys.Sign(ins.signedData)
+	ins.Unlock()
 	_, err := w.Write(signature)
 	if err != nil {
 		return err
 	}
+	ins.Lock()
+	defer ins.Unlock()
 	if ins.stat == Data {
 		ins.stat = Signed
 	}
@@ -90,19 +118,18 @@ func (ins *instance) SendSignature(w io.Writer) error {
 }
 
 func (ins *instance) AcceptProof(r io.Reader) error {
+	nProc := uint16(ins.keys.Length())
+	data, err := readProof(r, nProc)
 	ins.Lock()
 	defer ins.Unlock()
+	if err != nil {
+		return err
+	}
 	if ins.stat == Unknown {
 		return errors.New("cannot accept proof of unknown data")
 	}
-	nProc := uint16(ins.keys.Length())
 	proof := multi.NewSignature(crypto.MinimalQuorum(nProc), ins.signedData)
-	data := make([]byte, proof.MarshaledLength())
-	_, err := io.ReadFull(r, data)
-	if err != nil {
-		return err
-	}
-	_, err = proof.Unmarshal(data)
+	_, err = proof.Decode(data, nProc)
 	if err != nil {
 		return err
 	}
@@ -222,6 +249,37 @@ func newRaw(id uint64, data []byte, keys *multi.Keychain) *instance {
 	}
 }
 
+// proofVersion is the version of the wire format of proofs, sent before every proof.
+// Proofs of other versions are rejected.
+const proofVersion byte = 1
+
+// writeProof writes the proof in the following form
+// (1) proofVersion, 1 byte
+// (2) the multisignature encoded with EncodeCompressed
+func writeProof(w io.Writer, data []byte) error {
+	_, err := w.Write(append([]byte{proofVersion}, data...))
+	return err
+}
+
+// readProof reads a proof written with writeProof and returns the encoded multisignature.
+func readProof(r io.Reader, nProc uint16) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != proofVersion {
+		return nil, errors.New("unknown version of proof")
+	}
+	length := multi.EncodedLength(header[1], nProc)
+	if length == 0 {
+		return nil, errors.New("unknown format of proof")
+	}
+	data := make([]byte, length)
+	data[0] = header[1]
+	_, err := io.ReadFull(r, data[1:])
+	return data, err
+}
+
 func encodeUint32(w io.Writer, i uint32) error {
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, i)
@@ -24,18 +24,37 @@ type RMC struct {
 }
 
 // New creates a context for executing instances of the reliable multicast.
-func New(pubs []*bn256.VerificationKey, priv *bn256.SecretKey) *RMC {
+// It returns an error if the verification key corresponding to priv is not among pubs.
+func New(pubs []*bn256.VerificationKey, priv *bn256.SecretKey) (*RMC, error) {
+	keys, err := multi.NewKeychain(pubs, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &RMC{
+		keys: keys,
+		in:   map[uint64]*incoming{},
+		out:  map[uint64]*instance{},
+	}, nil
+}
+
+// NewVerifier creates a verify-only context, which can accept data and proofs of finished multicasts, but never signs anything.
+func NewVerifier(pubs []*bn256.VerificationKey) *RMC {
 	return &RMC{
-		keys: multi.NewKeychain(pubs, priv),
+		keys: multi.NewVerifier(pubs),
 		in:   map[uint64]*incoming{},
 		out:  map[uint64]*instance{},
 	}
 }
 
+var errVerifyOnly = errors.New("verify-only rmc cannot sign")
+
 // InitiateRaw data signature gathering.
 // This should be used only when all participants already know the data,
 // and only want to produce a proof that it is agreed between them.
 func (rmc *RMC) InitiateRaw(id uint64, data []byte) error {
+	if !rmc.keys.CanSign() {
+		return errVerifyOnly
+	}
 	_, err := rmc.newRawInstance(id, data)
 	return err
 }
@@ -57,6 +76,9 @@ func (rmc *RMC) AcceptData(id uint64, pid uint16, r io.Reader) ([]byte, error) {
 // SendSignature writes the signature associated with id to w.
 // The signature signs the data.
 func (rmc *RMC) SendSignature(id uint64, w io.Writer) error {
+	if !rmc.keys.CanSign() {
+		return errVerifyOnly
+	}
 	ins, err := rmc.get(id)
 	if err != nil {
 		return err
@@ -82,12 +104,17 @@ func (rmc *RMC) SendData(id uint64, data []byte, w io.Writer) error {
 		}
 		return out.SendData(w)
 	}
+	if !rmc.keys.CanSign() {
+		return errVerifyOnly
+	}
 	out := rmc.newOutgoingInstance(id, data)
 	return out.SendData(w)
 }
 
-// AcceptSignature reads a signature from r and verifies it represents pid signing the data associated with id.
+// AcceptSignature reads a signature from r representing pid signing the data associated with id.
 // It returns true when the signature is exactly threshold-th signature gathered.
+// The signatures are verified together once threshold of them is gathered. If that fails,
+// the incorrect signatures are dropped, and an error is returned only if the one from pid is among them.
 func (rmc *RMC) AcceptSignature(id uint64, pid uint16, r io.Reader) (bool, error) {
 	ins, err := rmc.get(id)
 	if err != nil {
@@ -96,7 +123,7 @@ func (rmc *RMC) AcceptSignature(id uint64, pid uint16, r io.Reader) (bool, error
 	return ins.AcceptSignature(pid, r)
 }
 
-// SendProof writes the proof associated with id to w.
+// SendProof writes the proof associated with id to w, preceded by the version of its wire format.
 func (rmc *RMC) SendProof(id uint64, w io.Writer) error {
 	ins, err := rmc.get(id)
 	if err != nil {
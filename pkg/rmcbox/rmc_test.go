@@ -1,6 +1,7 @@
 package rmcbox_test
 
 import (
+	"bytes"
 	"io"
 	"sync"
 
@@ -8,6 +9,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"gitlab.com/alephledger/core-go/pkg/crypto/bn256"
+	"gitlab.com/alephledger/core-go/pkg/crypto/multi"
 	. "gitlab.com/alephledger/core-go/pkg/rmcbox"
 )
 
@@ -17,13 +19,15 @@ var _ = Describe("Rmc", func() {
 		data    []byte
 		readers [][]io.Reader
 		writers [][]io.Writer
+		pubs    []*bn256.VerificationKey
+		privs   []*bn256.SecretKey
 		n       uint16
 	)
 	BeforeEach(func() {
 		data = []byte("19890604")
 		n = 10
-		pubs := make([]*bn256.VerificationKey, n)
-		privs := make([]*bn256.SecretKey, n)
+		pubs = make([]*bn256.VerificationKey, n)
+		privs = make([]*bn256.SecretKey, n)
 		rmcs = make([]*RMC, n)
 		readers = make([][]io.Reader, n)
 		writers = make([][]io.Writer, n)
@@ -33,7 +37,9 @@ var _ = Describe("Rmc", func() {
 			Expect(err).NotTo(HaveOccurred())
 		}
 		for i := range rmcs {
-			rmcs[i] = New(pubs, privs[i])
+			var err error
+			rmcs[i], err = New(pubs, privs[i])
+			Expect(err).NotTo(HaveOccurred())
 			readers[i] = make([]io.Reader, n)
 			writers[i] = make([]io.Writer, n)
 			for j := range readers[i] {
@@ -88,4 +94,117 @@ var _ = Describe("Rmc", func() {
 		}
 		wg.Wait()
 	})
+	It("Should find and drop incorrect signatures when the proof fails", func() {
+		id := uint64(21037)
+		proto := rmcs[0]
+		buf := &bytes.Buffer{}
+		Expect(proto.SendData(id, data, buf)).To(Succeed())
+		sent := buf.Bytes()
+		_, err := proto.AcceptSignature(id, 1, bytes.NewReader(privs[1].Sign(data).Marshal()))
+		Expect(err).NotTo(HaveOccurred())
+		var done bool
+		for i := uint16(2); !done; i++ {
+			_, err := rmcs[i].AcceptData(id, 0, bytes.NewReader(sent))
+			Expect(err).NotTo(HaveOccurred())
+			sgn := &bytes.Buffer{}
+			Expect(rmcs[i].SendSignature(id, sgn)).To(Succeed())
+			done, err = proto.AcceptSignature(id, i, sgn)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		// The incorrect signature of 1 was dropped, so one more signature was needed.
+		Expect(done).To(BeTrue())
+		Expect(proto.Status(id)).To(Equal(Finished))
+		proof := &bytes.Buffer{}
+		Expect(proto.SendProof(id, proof)).To(Succeed())
+		_, err = rmcs[9].AcceptData(id, 0, bytes.NewReader(sent))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rmcs[9].AcceptProof(id, proof)).To(Succeed())
+	})
+	It("Should blame the sender of a signature completing an incorrect proof", func() {
+		id := uint64(21037)
+		proto := rmcs[0]
+		buf := &bytes.Buffer{}
+		Expect(proto.SendData(id, data, buf)).To(Succeed())
+		sent := buf.Bytes()
+		for i := uint16(1); i < 6; i++ {
+			_, err := rmcs[i].AcceptData(id, 0, bytes.NewReader(sent))
+			Expect(err).NotTo(HaveOccurred())
+			sgn := &bytes.Buffer{}
+			Expect(rmcs[i].SendSignature(id, sgn)).To(Succeed())
+			_, err = proto.AcceptSignature(id, i, sgn)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		done, err := proto.AcceptSignature(id, 6, bytes.NewReader(privs[6].Sign(data).Marshal()))
+		Expect(err).To(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(proto.Status(id)).NotTo(Equal(Finished))
+	})
+	It("Should reject signatures from processes out of range", func() {
+		id := uint64(21037)
+		proto := rmcs[0]
+		Expect(proto.SendData(id, data, &bytes.Buffer{})).To(Succeed())
+		_, err := proto.AcceptSignature(id, n, bytes.NewReader(privs[1].Sign(data).Marshal()))
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should check proofs with a verify-only rmc but never sign", func() {
+		id := uint64(21037)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CorrectCast(0, id)
+		}()
+		for i := 1; i < int(n); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				CorrectReceive(uint16(i), 0, id)
+			}(i)
+		}
+		wg.Wait()
+		finished := &bytes.Buffer{}
+		Expect(rmcs[0].SendFinished(id, finished)).To(Succeed())
+		verifier := NewVerifier(pubs)
+		received, err := verifier.AcceptFinished(id, 0, finished)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(Equal(data))
+		Expect(verifier.Status(id)).To(Equal(Finished))
+		Expect(verifier.SendSignature(id, &bytes.Buffer{})).NotTo(Succeed())
+		Expect(verifier.SendData(id+1, data, &bytes.Buffer{})).NotTo(Succeed())
+		Expect(verifier.InitiateRaw(id+2, data)).NotTo(Succeed())
+	})
+	It("Should reject proofs of an unknown version", func() {
+		id := uint64(21037)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CorrectCast(0, id)
+		}()
+		for i := 1; i < int(n); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				CorrectReceive(uint16(i), 0, id)
+			}(i)
+		}
+		wg.Wait()
+		finished := &bytes.Buffer{}
+		Expect(rmcs[0].SendFinished(id, finished)).To(Succeed())
+		encoded := finished.Bytes()
+		proof := len(encoded) - multi.EncodedLength(multi.FormatCompressed, n) - 1
+		Expect(encoded[proof]).To(Equal(byte(1)))
+		encoded[proof] = 2
+		_, err := NewVerifier(pubs).AcceptFinished(id, 0, bytes.NewReader(encoded))
+		Expect(err).To(HaveOccurred())
+		encoded[proof] = 1
+		_, err = NewVerifier(pubs).AcceptFinished(id, 0, bytes.NewReader(encoded))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("Should not be created with a foreign secret key", func() {
+		_, priv, err := bn256.GenerateKeys()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = New(pubs, priv)
+		Expect(err).To(HaveOccurred())
+	})
 })
@@ -0,0 +1,4 @@
+package signing
+
+// MaxUnrequested exposes the limit of stored messages not requested locally, per member.
+const MaxUnrequested = maxUnrequested
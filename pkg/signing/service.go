@@ -0,0 +1,290 @@
+// Package signing implements a service producing threshold signatures of messages in cooperation with other committee members.
+//
+// When a message is requested locally, the service broadcasts its own share of the signature.
+// Shares received from other members are verified and stored, also for messages not requested locally yet,
+// so it does not matter who asks first. Only a bounded number of such messages is stored for every member.
+// As soon as enough valid shares are gathered, they are combined and the signature is delivered to everyone waiting for it.
+// A member never signs a message that was not requested locally.
+package signing
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	"gitlab.com/alephledger/core-go/pkg/network/secure"
+)
+
+// Key is a threshold key used for signing, e.g. a tss.ThresholdKey or a tss.WeakThresholdKey.
+type Key interface {
+	NProc() uint16
+	Threshold() uint16
+	CreateShare(msg []byte) *tss.Share
+	VerifyShare(share *tss.Share, msg []byte) bool
+	CombineShares(shares []*tss.Share) (*tss.Signature, bool)
+}
+
+// Service produces threshold signatures over the network.
+type Service struct {
+	pid      uint16
+	key      Key
+	netserv  network.Server
+	timeout  time.Duration
+	mx       sync.Mutex
+	requests map[string]*request
+	// unrequested counts the requests created by shares of each member and not requested locally yet.
+	unrequested map[uint16]int
+	quit        chan struct{}
+	stopOnce    sync.Once
+}
+
+// request gathers shares of the signature of a single message.
+type request struct {
+	msg       []byte
+	requested bool
+	shares    []*tss.Share
+	owners    map[uint16]bool
+	sgn       *tss.Signature
+	done      chan struct{}
+	creator   uint16
+	deadline  time.Time
+	expire    *time.Timer
+}
+
+// maxUnrequested bounds the number of messages not requested locally that are stored because of shares of a single member.
+const maxUnrequested = 16
+
+// New creates a signing service for the member pid holding the key.
+// Signing requests fail if no signature is produced within the timeout.
+// Information about a message is dropped when it was not requested for the timeout.
+func New(pid uint16, key Key, netserv network.Server, timeout time.Duration) *Service {
+	return &Service{
+		pid:         pid,
+		key:         key,
+		netserv:     netserv,
+		timeout:     timeout,
+		requests:    map[string]*request{},
+		unrequested: map[uint16]int{},
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start the service.
+func (s *Service) Start() error {
+	go s.listen()
+	return nil
+}
+
+// Stop the service. Pending requests fail.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+}
+
+// Sign returns the threshold signature of msg. Concurrent requests for the same message are served together.
+func (s *Service) Sign(msg []byte) (*tss.Signature, error) {
+	select {
+	case <-s.quit:
+		return nil, errors.New("service stopped")
+	default:
+	}
+	req, share := s.request(msg)
+	if share != nil {
+		go s.broadcast(msg, share)
+	}
+	select {
+	case <-req.done:
+		return req.sgn, nil
+	case <-time.After(s.timeout):
+		return nil, errors.New("signing timed out")
+	case <-s.quit:
+		return nil, errors.New("service stopped")
+	}
+}
+
+// request marks msg as requested locally. If that happens for the first time,
+// it adds our share to the request and returns it for broadcasting.
+func (s *Service) request(msg []byte) (*request, *tss.Share) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	req := s.get(msg, s.pid)
+	req.deadline = time.Now().Add(s.timeout)
+	req.expire.Reset(s.timeout)
+	if req.requested {
+		return req, nil
+	}
+	req.requested = true
+	if req.creator != s.pid {
+		s.unrequested[req.creator]--
+	}
+	share := s.key.CreateShare(msg)
+	if share != nil {
+		s.add(req, share)
+	}
+	return req, share
+}
+
+// get returns the request for msg, creating it on behalf of the given member if needed.
+// It returns nil if that member already caused too many requests not requested locally.
+// It has to be called under the lock.
+func (s *Service) get(msg []byte, creator uint16) *request {
+	key := string(msg)
+	if req, ok := s.requests[key]; ok {
+		return req
+	}
+	if creator != s.pid {
+		if s.unrequested[creator] >= maxUnrequested {
+			return nil
+		}
+		s.unrequested[creator]++
+	}
+	req := &request{
+		msg:      msg,
+		owners:   map[uint16]bool{},
+		done:     make(chan struct{}),
+		creator:  creator,
+		deadline: time.Now().Add(s.timeout),
+	}
+	req.expire = time.AfterFunc(s.timeout, func() {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+		// The timer might have fired just before a request extended the deadline.
+		if s.requests[key] != req || time.Now().Before(req.deadline) {
+			return
+		}
+		delete(s.requests, key)
+		if !req.requested && req.creator != s.pid {
+			s.unrequested[req.creator]--
+		}
+	})
+	s.requests[key] = req
+	return req
+}
+
+// add adds a verified share to the request, and combines the signature when there are enough shares.
+// It has to be called under the lock.
+func (s *Service) add(req *request, share *tss.Share) {
+	if req.sgn != nil || req.owners[share.Owner()] {
+		return
+	}
+	req.owners[share.Owner()] = true
+	req.shares = append(req.shares, share)
+	if uint16(len(req.shares)) < s.key.Threshold() {
+		return
+	}
+	if sgn, ok := s.key.CombineShares(req.shares); ok {
+		req.sgn = sgn
+		close(req.done)
+	}
+}
+
+// broadcast sends our share of the signature of msg to all the other members.
+func (s *Service) broadcast(msg []byte, share *tss.Share) {
+	data := encodeShare(msg, share)
+	for pid := uint16(0); pid < s.key.NProc(); pid++ {
+		if pid == s.pid {
+			continue
+		}
+		go func(pid uint16) {
+			conn, err := s.netserv.Dial(pid)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if _, err := conn.Write(data); err == nil {
+				conn.Flush()
+			}
+		}(pid)
+	}
+}
+
+func (s *Service) listen() {
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		conn, err := s.netserv.Listen()
+		if err != nil {
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Service) handle(conn network.Connection) {
+	defer conn.Close()
+	msg, share, err := decodeShare(conn)
+	if err != nil {
+		return
+	}
+	if share.Owner() >= s.key.NProc() || share.Owner() == s.pid {
+		return
+	}
+	if remote, ok := secure.RemotePid(conn); ok && remote != share.Owner() {
+		return
+	}
+	if !s.key.VerifyShare(share, msg) {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if req := s.get(msg, share.Owner()); req != nil {
+		s.add(req, share)
+	}
+}
+
+// encodeShare encodes a share of the signature of msg in the following form
+// (1) length of msg, 4 bytes as uint32
+// (2) msg
+// (3) length of the marshalled share, 4 bytes as uint32
+// (4) marshalled share
+func encodeShare(msg []byte, share *tss.Share) []byte {
+	shareData := share.Marshal()
+	data := make([]byte, 4, 8+len(msg)+len(shareData))
+	binary.LittleEndian.PutUint32(data, uint32(len(msg)))
+	data = append(data, msg...)
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(len(shareData)))
+	data = append(data, buf...)
+	return append(data, shareData...)
+}
+
+// maxMessageLength bounds the size of messages accepted from other members.
+const maxMessageLength = 1 << 20
+
+func decodeShare(r io.Reader) ([]byte, *tss.Share, error) {
+	msg, err := readChunk(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	shareData, err := readChunk(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	share := new(tss.Share)
+	if err := share.Unmarshal(shareData); err != nil {
+		return nil, nil, err
+	}
+	return msg, share, nil
+}
+
+func readChunk(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(buf)
+	if n > maxMessageLength {
+		return nil, errors.New("message too long")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
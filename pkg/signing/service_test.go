@@ -0,0 +1,122 @@
+package signing_test
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gitlab.com/alephledger/core-go/pkg/crypto/tss"
+	"gitlab.com/alephledger/core-go/pkg/network"
+	. "gitlab.com/alephledger/core-go/pkg/signing"
+	"gitlab.com/alephledger/core-go/pkg/tests"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		n        uint16
+		keys     []*tss.WeakThresholdKey
+		services []*Service
+		netservs []network.Server
+		msg      []byte
+	)
+	BeforeEach(func() {
+		n = 4
+		msg = []byte("19890604")
+		keys = make([]*tss.WeakThresholdKey, n)
+		services = make([]*Service, n)
+		netservs = tests.NewNetwork(int(n), 200*time.Millisecond)
+		for i := uint16(0); i < n; i++ {
+			keys[i] = tss.SeededWTK(n, i, 1917, nil)
+			services[i] = New(i, keys[i], netservs[i], 2*time.Second)
+			Expect(services[i].Start()).To(Succeed())
+		}
+	})
+	AfterEach(func() {
+		for _, s := range services {
+			s.Stop()
+		}
+		tests.CloseNetwork(netservs)
+	})
+	// sign requests the signature of msg on the given members concurrently, and returns the results.
+	sign := func(pids ...uint16) ([]*tss.Signature, []error) {
+		sgns := make([]*tss.Signature, len(pids))
+		errs := make([]error, len(pids))
+		var wg sync.WaitGroup
+		for i, pid := range pids {
+			wg.Add(1)
+			go func(i int, pid uint16) {
+				defer wg.Done()
+				sgns[i], errs[i] = services[pid].Sign(msg)
+			}(i, pid)
+		}
+		wg.Wait()
+		return sgns, errs
+	}
+	It("should deliver the same valid signature to all members", func() {
+		sgns, errs := sign(0, 1, 2, 3)
+		for i := range sgns {
+			Expect(errs[i]).NotTo(HaveOccurred())
+			Expect(keys[0].VerifySignature(sgns[i], msg)).To(BeTrue())
+			Expect(sgns[i].Marshal()).To(Equal(sgns[0].Marshal()))
+		}
+	})
+	It("should serve concurrent requests for the same message together", func() {
+		sgns, errs := sign(0, 0, 0, 1)
+		for i := range sgns {
+			Expect(errs[i]).NotTo(HaveOccurred())
+		}
+		Expect(sgns[1]).To(BeIdenticalTo(sgns[0]))
+		Expect(sgns[2]).To(BeIdenticalTo(sgns[0]))
+		Expect(keys[0].VerifySignature(sgns[0], msg)).To(BeTrue())
+		Expect(sgns[3].Marshal()).To(Equal(sgns[0].Marshal()))
+	})
+	It("should use shares received before the local request", func() {
+		done := make(chan error)
+		go func() {
+			_, err := services[2].Sign(msg)
+			done <- err
+		}()
+		time.Sleep(500 * time.Millisecond)
+		sgns, errs := sign(3)
+		Expect(errs[0]).NotTo(HaveOccurred())
+		Expect(keys[0].VerifySignature(sgns[0], msg)).To(BeTrue())
+		Expect(<-done).NotTo(HaveOccurred())
+	})
+	It("should time out when other members do not request the message", func() {
+		_, errs := sign(1)
+		Expect(errs[0]).To(HaveOccurred())
+	})
+	It("should drop shares of too many messages not requested locally", func() {
+		msgs := make([][]byte, MaxUnrequested+1)
+		for i := range msgs {
+			msgs[i] = []byte{byte(i)}
+			data := make([]byte, 4, 9)
+			binary.LittleEndian.PutUint32(data, 1)
+			data = append(data, msgs[i]...)
+			shareData := keys[1].CreateShare(msgs[i]).Marshal()
+			data = append(data, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(data[5:], uint32(len(shareData)))
+			data = append(data, shareData...)
+			conn, err := netservs[1].Dial(0)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = conn.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+			conn.Flush()
+			conn.Close()
+			time.Sleep(50 * time.Millisecond)
+		}
+		time.Sleep(500 * time.Millisecond)
+		_, err := services[0].Sign(msgs[0])
+		Expect(err).NotTo(HaveOccurred())
+		_, err = services[0].Sign(msgs[MaxUnrequested])
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail after being stopped", func() {
+		services[0].Stop()
+		_, errs := sign(0)
+		Expect(errs[0]).To(HaveOccurred())
+	})
+})